@@ -0,0 +1,44 @@
+package fauth
+
+import (
+	"context"
+	"net/http"
+)
+
+const upgradedContextKey contextKey = "upgraded"
+
+// Upgraded reports whether the user of the verified token upgraded an anonymous account to a permanent one,
+// keeping its UID. The second value is false when it's unknown, e.g. because `Engine.WasAnonymous` isn't set.
+//
+// It's resolved from the following sources, in order:
+//   - The sign-in provider of the token: users signed in with the `anonymous` provider haven't upgraded yet.
+//   - The user record fetched by `VerifyIDTokenWithUser`, if any: users without linked providers haven't upgraded yet.
+//   - The `Engine.WasAnonymous` func, reporting whether the UID was ever anonymous. Neither the tokens nor the user
+//     records keep that history, so the app has to record it, e.g. when it first sees an anonymous token.
+func Upgraded(ctx context.Context) (upgraded bool, ok bool) {
+	upgraded, ok = ctx.Value(upgradedContextKey).(bool)
+	return upgraded, ok
+}
+
+// resolveUpgrade stores whether the user of the verified token upgraded an anonymous account in the request context.
+func (e *Engine) resolveUpgrade(r *http.Request, data any) *http.Request {
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return r
+	}
+	anonymous := token.Firebase.SignInProvider == "anonymous"
+	if res, ok := data.(*Result); ok && res.User != nil && len(res.User.ProviderUserInfo) == 0 {
+		anonymous = true
+	}
+	if anonymous {
+		return r.WithContext(context.WithValue(r.Context(), upgradedContextKey, false))
+	}
+	if e.WasAnonymous == nil {
+		return r
+	}
+	was, err := e.WasAnonymous(r.Context(), token.UID)
+	if err != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), upgradedContextKey, was))
+}
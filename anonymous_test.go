@@ -0,0 +1,47 @@
+package fauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestUpgraded(t *testing.T) {
+	if _, ok := fauth.Upgraded(context.Background()); ok {
+		t.Fatal("the upgrade status shouldn't be known")
+	}
+
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.WasAnonymous = func(ctx context.Context, uid string) (bool, error) {
+			switch uid {
+			case "upgraded":
+				return true, nil
+			case "broken":
+				return false, errors.New("db is down")
+			}
+			return false, nil
+		}
+	})
+	var upgraded, ok bool
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		upgraded, ok = fauth.Upgraded(r.Context())
+	})
+	tests := []struct {
+		uid, provider string
+		upgraded, ok  bool
+	}{
+		{"upgraded", "google.com", true, true},
+		{"upgraded", "anonymous", false, true},
+		{"permanent", "password", false, true},
+		{"broken", "password", false, false},
+	}
+	for _, tt := range tests {
+		serveBearer(h, sign(map[string]any{"sub": tt.uid, "firebase": map[string]any{"sign_in_provider": tt.provider}}))
+		if upgraded != tt.upgraded || ok != tt.ok {
+			t.Fatalf("%s, %s: expected %t, %t, got %t, %t", tt.uid, tt.provider, tt.upgraded, tt.ok, upgraded, ok)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package fauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth/internal/jwt"
+)
+
+// AppCheckHeader is the header the Firebase client SDKs send the App Check token in.
+const AppCheckHeader = "X-Firebase-AppCheck"
+
+const (
+	appCheckIssuer  = "https://firebaseappcheck.googleapis.com/"
+	appCheckJWKSURL = "https://firebaseappcheck.googleapis.com/v1/jwks"
+)
+
+// AppCheck verifies the Firebase App Check tokens, making sure the requests come from your genuine apps,
+// not just from authenticated users. Combine its `OnAuth` with the Firebase verifier using `All`
+// to require both a valid ID token and a valid App Check token:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.ProjectID = "my-project-id"
+//		e.OnAuth = fauth.All(fauth.VerifyIDToken, fauth.VerifyAppCheckToken(""))
+//	})
+//
+// The tokens are verified against the public keys of App Check, fetched from its JWKS endpoint and cached,
+// along with their issuer, audience and expiry. The failures are told apart from the ID token ones by their
+// codes, see `ErrorCode`, and unlike them, they aren't let through by `Engine.Optional`.
+type AppCheck struct {
+	// ProjectID is the ID of the Firebase project the tokens must be issued for, defaulting to `Engine.ProjectID`.
+	ProjectID string
+	// Header carries the token, defaulting to `AppCheckHeader`.
+	Header string
+	// JWKSURL serves the public keys of App Check, defaulting to the App Check JWKS endpoint.
+	JWKSURL string
+	// Client fetches the public keys, defaulting to `http.DefaultClient`.
+	Client *http.Client
+
+	keys keySet
+}
+
+// appCheck is the AppCheck of `VerifyAppCheckToken`, sharing the public keys between the verifiers.
+var appCheck = &AppCheck{}
+
+// VerifyAppCheckToken returns an `Engine.OnAuth` func verifying the App Check token carried by the header,
+// `X-Firebase-AppCheck` if empty, for the `Engine.ProjectID`, see `AppCheck`.
+func VerifyAppCheckToken(headerName string) OnAuthFunc {
+	return func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+		return appCheck.verify(r, headerName)
+	}
+}
+
+// OnAuth is an `Engine.OnAuth` func verifying the App Check token of the request, returning its `Claims`,
+// the `sub` claim being the ID of the app. Requests without it are rejected with `ErrNoAppCheckToken`,
+// the ones with an invalid or expired token with `ErrInvalidAppCheckToken`, both answered with 401 Unauthorized.
+func (c *AppCheck) OnAuth(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	return c.verify(r, c.Header)
+}
+
+func (c *AppCheck) verify(r *http.Request, header string) (any, error) {
+	if header == "" {
+		header = AppCheckHeader
+	}
+	projectID := c.ProjectID
+	if projectID == "" {
+		projectID = scopeFrom(r.Context()).engine.ProjectID
+	}
+	if projectID == "" {
+		return nil, withStatus(http.StatusInternalServerError, errors.New("fauth: no project ID to verify the App Check token, set Engine.ProjectID"))
+	}
+	raw := r.Header.Get(header)
+	if raw == "" {
+		return nil, appCheckError(fmt.Errorf("%w in header: %s", ErrNoAppCheckToken, header))
+	}
+	t, err := jwt.Parse(raw)
+	if err != nil {
+		return nil, appCheckError(fmt.Errorf("%w: %w", ErrInvalidAppCheckToken, err))
+	}
+	key, err := c.keys.key(r.Context(), t.KeyID(), c.fetchKeys)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			// Not wrapped, so the error isn't mistaken for an invalid ID token.
+			return nil, appCheckError(fmt.Errorf("%w: %v", ErrInvalidAppCheckToken, err))
+		}
+		return nil, err
+	}
+	if err := t.Verify(key); err != nil {
+		return nil, appCheckError(fmt.Errorf("%w: %w", ErrInvalidAppCheckToken, err))
+	}
+	var claims Claims
+	if err := json.Unmarshal(t.Payload, &claims); err != nil {
+		return nil, appCheckError(fmt.Errorf("%w: invalid payload: %w", ErrInvalidAppCheckToken, err))
+	}
+	if iss, _ := claims.String("iss"); !strings.HasPrefix(iss, appCheckIssuer) {
+		return nil, appCheckError(fmt.Errorf("%w: unexpected issuer %s", ErrInvalidAppCheckToken, iss))
+	}
+	if sub, _ := claims.String("sub"); sub == "" {
+		return nil, appCheckError(fmt.Errorf("%w: empty sub claim", ErrInvalidAppCheckToken))
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, appCheckError(fmt.Errorf("%w: missing exp claim", ErrInvalidAppCheckToken))
+	}
+	if int64(exp) <= Now(r.Context()).Unix() {
+		return nil, appCheckError(fmt.Errorf("%w: expired at: %d", ErrInvalidAppCheckToken, int64(exp)))
+	}
+	for _, aud := range audiences(&auth.Token{Claims: claims}) {
+		if aud == "projects/"+projectID {
+			return claims, nil
+		}
+	}
+	return nil, appCheckError(fmt.Errorf("%w: expected audience projects/%s", ErrInvalidAppCheckToken, projectID))
+}
+
+func (c *AppCheck) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	url := c.JWKSURL
+	if url == "" {
+		url = appCheckJWKSURL
+	}
+	return fetchJWKS(ctx, c.Client, url, "App Check")
+}
+
+// appCheckError answers the App Check failures with 401 Unauthorized, without letting them through
+// as anonymous requests with `Engine.Optional`.
+func appCheckError(err error) error {
+	return &statusError{code: http.StatusUnauthorized, err: err}
+}
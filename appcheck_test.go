@@ -0,0 +1,89 @@
+package fauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/internal/jwt"
+)
+
+func TestAppCheck(t *testing.T) {
+	appCheckKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwt.JWK("ac", &appCheckKey.PublicKey)}})
+	}))
+	t.Cleanup(srv.Close)
+
+	ac := &fauth.AppCheck{JWKSURL: srv.URL}
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.ProjectID = "fauthtest"
+		e.Optional = true
+		e.OnAuth = fauth.All(e.OnAuth, ac.OnAuth)
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	appCheckToken := func(claims map[string]any, key *rsa.PrivateKey) string {
+		c := map[string]any{
+			"iss": "https://firebaseappcheck.googleapis.com/123456",
+			"aud": []string{"projects/123456", "projects/fauthtest"},
+			"sub": "1:123456:web:abc",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range claims {
+			c[k] = v
+		}
+		s, err := jwt.Sign(map[string]any{"kid": "ac"}, c, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	firebaseToken := sign(map[string]any{"sub": "uid", "aud": "fauthtest", "iss": "https://securetoken.google.com/fauthtest"})
+	tests := []struct {
+		appCheck string
+		code     int
+		err      string
+	}{
+		{appCheckToken(nil, appCheckKey), http.StatusOK, ""},
+		{"", http.StatusUnauthorized, "no_app_check_token"},
+		{"garbage", http.StatusUnauthorized, "invalid_app_check_token"},
+		{appCheckToken(nil, testKey), http.StatusUnauthorized, "invalid_app_check_token"},
+		{appCheckToken(map[string]any{"aud": []string{"projects/other"}}, appCheckKey), http.StatusUnauthorized, "invalid_app_check_token"},
+		{appCheckToken(map[string]any{"iss": "https://evil.example.com/"}, appCheckKey), http.StatusUnauthorized, "invalid_app_check_token"},
+		{appCheckToken(map[string]any{"exp": time.Now().Add(-time.Minute).Unix()}, appCheckKey), http.StatusUnauthorized, "invalid_app_check_token"},
+		{appCheckToken(map[string]any{"sub": ""}, appCheckKey), http.StatusUnauthorized, "invalid_app_check_token"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r.Header.Set("Authorization", "Bearer "+firebaseToken)
+		if tt.appCheck != "" {
+			r.Header.Set(fauth.AppCheckHeader, tt.appCheck)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+
+	withAppCheck, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.ProjectID = "fauthtest"
+		e.OnAuth = fauth.All(e.OnAuth, fauth.VerifyAppCheckToken("X-App-Check"))
+	})
+	w := serveBearer(withAppCheck(func(w http.ResponseWriter, r *http.Request) {}), firebaseToken)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("X-Auth-Error") != "no_app_check_token" {
+		t.Fatalf("expected no_app_check_token, got %d, %q", w.Code, w.Header().Get("X-Auth-Error"))
+	}
+}
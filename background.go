@@ -0,0 +1,95 @@
+package fauth
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const activeQueueSize = 256
+
+// firestoreGracePeriod is how long the Firestore clients replaced by `Authenticator.Reload` are left
+// to the in-flight requests before being closed.
+const firestoreGracePeriod = time.Minute
+
+// activity is a successfully verified request, waiting for the `Engine.OnActive` hook.
+type activity struct {
+	ctx context.Context
+	uid string
+}
+
+// start starts the background work of the Authenticator, stopped by `Close`.
+func (a *Authenticator) start(ctx context.Context) {
+	ctx, a.stop = context.WithCancel(context.WithoutCancel(ctx))
+	a.done = ctx.Done()
+	if a.engine.KeyRefreshInterval > 0 {
+		a.wg.Add(1)
+		go a.refreshKeys(ctx)
+	}
+	if a.engine.OnActive != nil {
+		a.active = make(chan activity, activeQueueSize)
+		a.wg.Add(1)
+		go a.runActive(ctx)
+	}
+}
+
+// Close stops the background work of the Authenticator, e.g. the key refresh enabled by `Engine.KeyRefreshInterval`,
+// waiting for it to finish, and closes the Firestore clients enabled by `Engine.InjectFirestore`, including
+// the ones replaced by `Reload` and still in their grace period.
+// The pending `Engine.OnActive` calls are dropped. It's safe to call Close more than once.
+func (a *Authenticator) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		a.stop()
+		a.wg.Wait()
+		if fs := a.current().firestore; fs != nil {
+			err = fs.Close()
+		}
+	})
+	return err
+}
+
+// retire closes the Firestore client replaced by `Reload` once the in-flight requests had the `firestoreGracePeriod`
+// to finish using it, or as soon as the Authenticator is closed.
+func (a *Authenticator) retire(fs *firestore.Client) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		t := time.NewTimer(firestoreGracePeriod)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-a.done:
+		}
+		_ = fs.Close()
+	}()
+}
+
+// markActive queues the verified request for the `Engine.OnActive` hook, dropping it if the queue is full.
+func (a *Authenticator) markActive(ctx context.Context, data any) {
+	if a.active == nil {
+		return
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return
+	}
+	select {
+	case a.active <- activity{ctx: context.WithoutCancel(ctx), uid: token.UID}:
+	default:
+		a.engine.metric("active_dropped", 1)
+	}
+}
+
+func (a *Authenticator) runActive(ctx context.Context) {
+	defer a.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case act := <-a.active:
+			a.engine.OnActive(act.ctx, act.uid)
+		}
+	}
+}
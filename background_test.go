@@ -0,0 +1,71 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestOnActive(t *testing.T) {
+	_, sign := offlineAuth(t)
+	uids := make(chan string)
+	var dropped atomic.Int64
+	a, err := fauth.NewAuthenticator(context.Background(), fauthtest.NewVerifier(&testKey.PublicKey).Option(), func(e *fauth.Engine) {
+		e.OnActive = func(ctx context.Context, uid string) {
+			uids <- uid
+		}
+		e.OnMetric = func(name string, value float64) {
+			if name == "active_dropped" {
+				dropped.Add(int64(value))
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := a.Wrap(func(w http.ResponseWriter, r *http.Request) {})
+
+	serveBearer(h, sign(map[string]any{"sub": "uid"}))
+	select {
+	case uid := <-uids:
+		if uid != "uid" {
+			t.Fatalf("invalid uid: %s", uid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnActive should be called")
+	}
+
+	// The worker is stuck on the unbuffered channel, so the queue fills up and the calls are dropped,
+	// without blocking the requests.
+	jwt := sign(map[string]any{"sub": "uid"})
+	for i := 0; i < 300; i++ {
+		if w := serveBearer(h, jwt); w.Code != http.StatusOK {
+			t.Fatalf("invalid status: %d", w.Code)
+		}
+	}
+	if dropped.Load() == 0 {
+		t.Fatal("the calls should be dropped under backpressure")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = a.Close()
+	}()
+	// Unblocks the worker, letting it notice it's closed.
+	go func() {
+		for range uids {
+		}
+	}()
+	select {
+	case <-done:
+		close(uids)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close should stop the worker")
+	}
+}
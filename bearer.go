@@ -0,0 +1,108 @@
+package fauth
+
+import (
+	"errors"
+	"strings"
+)
+
+var errInvalidAuthParams = errors.New("invalid auth-params")
+
+// parseAuthParams validates a comma-separated list of RFC 7235 auth-params, e.g. `realm="api", foo=bar`.
+// The params themselves are ignored.
+func parseAuthParams(s string) error {
+	i := 0
+	for i < len(s) {
+		// Skip the optional whitespace and empty list elements.
+		if s[i] == ' ' || s[i] == '\t' || s[i] == ',' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && isTokenChar(s[i]) {
+			i++
+		}
+		if i == start {
+			return errInvalidAuthParams
+		}
+		i = skipWhitespace(s, i)
+		if i == len(s) || s[i] != '=' {
+			return errInvalidAuthParams
+		}
+		i = skipWhitespace(s, i+1)
+		if i == len(s) {
+			return errInvalidAuthParams
+		}
+		if s[i] == '"' {
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(s) {
+				return errInvalidAuthParams
+			}
+			i++
+		} else {
+			start = i
+			for i < len(s) && isTokenChar(s[i]) {
+				i++
+			}
+			if i == start {
+				return errInvalidAuthParams
+			}
+		}
+		i = skipWhitespace(s, i)
+		if i < len(s) && s[i] != ',' {
+			return errInvalidAuthParams
+		}
+	}
+	return nil
+}
+
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// isTokenChar reports whether c is an RFC 7230 tchar.
+func isTokenChar(c byte) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// LooksLikeJWT reports whether the token is structurally a compact JWS, i.e. three dot-separated segments
+// of base64url characters. It's a cheap check, rejecting garbage before it reaches the verifier, and a lenient one:
+// padding is tolerated and the signature segment may be empty, as in the unsigned tokens of the Auth Emulator.
+func LooksLikeJWT(token string) bool {
+	segments, empty := 1, true
+	for i := 0; i < len(token); i++ {
+		c := token[i]
+		switch {
+		case c == '.':
+			if empty {
+				return false
+			}
+			segments++
+			if segments > 3 {
+				return false
+			}
+			// Only the header and the payload have to be non-empty.
+			empty = segments < 3
+		case isBase64URLChar(c):
+			empty = false
+		default:
+			return false
+		}
+	}
+	return segments == 3
+}
+
+func isBase64URLChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '='
+}
@@ -0,0 +1,53 @@
+package fauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// ErrBindingMismatch is returned when the token isn't bound to the TLS client certificate of the request.
+var ErrBindingMismatch = errors.New("fauth: token binding mismatch")
+
+// CertificateBinding verifies the token is bound to the TLS client certificate of the request,
+// following RFC 8705: the `cnf` claim of the token must carry the `x5t#S256` thumbprint,
+// the base64url-encoded SHA-256 hash of the DER-encoded certificate presented by the client.
+// It's meant to be used as the `Engine.VerifyBinding` hook of mTLS services:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.VerifyBinding = fauth.CertificateBinding
+//	})
+//
+// The `cnf` claim has to be set on the user using the custom claims of the Firebase Admin SDK.
+func CertificateBinding(r *http.Request, token *auth.Token) error {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: no client certificate", ErrBindingMismatch)
+	}
+	thumbprint, ok := Claims(token.Claims).String("cnf.x5t#S256")
+	if !ok {
+		return fmt.Errorf("%w: cnf.x5t#S256", ErrMissingClaim)
+	}
+	sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+	want := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(want)) != 1 {
+		return ErrBindingMismatch
+	}
+	return nil
+}
+
+// verifyBinding runs the `Engine.VerifyBinding` hook against the verified token, if any.
+func (e *Engine) verifyBinding(r *http.Request, data any) error {
+	if e.VerifyBinding == nil {
+		return nil
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return fmt.Errorf("%w: no token to check the binding against", ErrBindingMismatch)
+	}
+	return e.VerifyBinding(r, token)
+}
@@ -0,0 +1,82 @@
+package fauth_test
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestCertificateBinding(t *testing.T) {
+	cert := &x509.Certificate{Raw: []byte("client certificate")}
+	sum := sha256.Sum256(cert.Raw)
+	thumbprint := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	withTLS := httptest.NewRequest("", "https://www.example.com", nil)
+	withTLS.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	withoutTLS := httptest.NewRequest("", "http://www.example.com", nil)
+
+	bound := &auth.Token{Claims: map[string]any{"cnf": map[string]any{"x5t#S256": thumbprint}}}
+	tests := []struct {
+		name  string
+		r     *http.Request
+		token *auth.Token
+		err   error
+	}{
+		{"bound", withTLS, bound, nil},
+		{"no certificate", withoutTLS, bound, fauth.ErrBindingMismatch},
+		{"no claim", withTLS, &auth.Token{}, fauth.ErrMissingClaim},
+		{"other certificate", withTLS, &auth.Token{Claims: map[string]any{
+			"cnf": map[string]any{"x5t#S256": "bm9wZQ"},
+		}}, fauth.ErrBindingMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fauth.CertificateBinding(tt.r, tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyBinding(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.VerifyBinding = func(r *http.Request, token *auth.Token) error {
+			if r.Header.Get("X-Bound-To") != token.UID {
+				return fauth.ErrBindingMismatch
+			}
+			return nil
+		}
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	jwt := sign(map[string]any{"sub": "uid"})
+
+	tests := []struct {
+		boundTo string
+		code    int
+	}{
+		{"uid", http.StatusOK},
+		{"other", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("Authorization", "Bearer "+jwt)
+		r.Header.Set("X-Bound-To", tt.boundTo)
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%s: invalid status: %d", tt.boundTo, w.Code)
+		}
+		if tt.code != http.StatusOK && w.Header().Get("X-Auth-Error") != "binding_mismatch" {
+			t.Fatalf("invalid error code: %s", w.Header().Get("X-Auth-Error"))
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package fauth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is an LRU cache whose entries expire at given times, e.g. the user records or the verified tokens.
+type lruCache[V any] struct {
+	size  int
+	now   func() time.Time
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newLRUCache[V any](size int, now func() time.Time) *lruCache[V] {
+	return &lruCache[V]{size: size, now: now, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*lruEntry[V])
+	if !c.now().Before(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *lruCache[V]) add(key string, value V, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &lruEntry[V]{key: key, value: value, expires: expires}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(e)
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+}
@@ -0,0 +1,37 @@
+package fauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// challenge returns the RFC 6750 WWW-Authenticate challenge of the Engine for the error, e.g.
+// `Bearer realm="api", error="invalid_token", error_description="token expired"`.
+// Requests lacking the token are challenged without an error, e.g. `Bearer realm="api"`.
+func (e *Engine) challenge(err error) string {
+	scheme := e.AuthScheme
+	if strings.EqualFold(scheme, "bearer") {
+		scheme = "Bearer"
+	}
+	var params []string
+	if e.Realm != "" {
+		params = append(params, "realm="+quote(e.Realm))
+	}
+	switch {
+	case errors.Is(err, ErrNoToken):
+	case errors.Is(err, ErrMalformedHeader):
+		params = append(params, `error="invalid_request"`, "error_description="+quote(errorReason(err, http.StatusUnauthorized)))
+	default:
+		params = append(params, `error="invalid_token"`, "error_description="+quote(errorReason(err, http.StatusUnauthorized)))
+	}
+	if len(params) == 0 {
+		return scheme
+	}
+	return scheme + " " + strings.Join(params, ", ")
+}
+
+// quote returns s as an RFC 7230 quoted-string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
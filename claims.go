@@ -0,0 +1,143 @@
+package fauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// Claims is a typed view of the custom claims carried by a Firebase ID token.
+type Claims map[string]any
+
+const claimsContextKey contextKey = "claims"
+
+// WithClaims returns a copy of the `context.Context` with the given claims.
+// To retrieve them, use the `ClaimsFromContext` func.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext returns the claims of the verified token.
+// The default `Engine.OnData` stores them once per request, so nested handlers share the same value.
+// If they're missing, it falls back to the claims of the token returned by `AuthToken`.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	if claims, ok := ctx.Value(claimsContextKey).(Claims); ok {
+		return claims, true
+	}
+	token, ok := AuthToken(ctx)
+	if !ok || token == nil {
+		return nil, false
+	}
+	return token.Claims, true
+}
+
+// ClaimString returns the claim of the verified token with the given key if it's a string.
+// It's a shorthand for `ClaimsFromContext` followed by `Claims.String`, handy for claim-driven routing:
+//
+//	plan, _ := fauth.ClaimString(r.Context(), "plan")
+//	backends[plan].ServeHTTP(w, r)
+func ClaimString(ctx context.Context, key string) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.String(key)
+}
+
+// DecodeClaims decodes the claims of the token into a value of type T, e.g. a struct with JSON tags
+// mirroring the custom claims:
+//
+//	type AppClaims struct {
+//		OrgID string `json:"org_id"`
+//		Role  string `json:"role"`
+//	}
+//	claims, err := fauth.DecodeClaims[AppClaims](token)
+//
+// The claims are round-tripped through JSON, so the usual `encoding/json` rules apply: the claims without
+// a matching field are ignored, the fields without a matching claim keep their zero value, and a claim
+// of a type the field can't hold fails the decoding. It fails as well for a nil token.
+func DecodeClaims[T any](token *auth.Token) (T, error) {
+	var v T
+	if token == nil {
+		return v, errors.New("fauth: nil token")
+	}
+	b, err := json.Marshal(token.Claims)
+	if err != nil {
+		return v, fmt.Errorf("fauth: failed to encode the claims: %w", err)
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("fauth: failed to decode the claims: %w", err)
+	}
+	return v, nil
+}
+
+// Get returns the claim with the given key.
+// Nested claims can be referenced using the dot notation, e.g. `org.id`.
+// Keys that contain dots themselves take precedence over the nested lookup.
+func (c Claims) Get(key string) (any, bool) {
+	if v, ok := c[key]; ok {
+		return v, true
+	}
+	var v any = map[string]any(c)
+	for _, k := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// String returns the claim with the given key if it's a string.
+func (c Claims) String(key string) (string, bool) {
+	v, ok := c.Get(key)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// Bool returns the claim with the given key if it's a bool.
+func (c Claims) Bool(key string) (bool, bool) {
+	v, ok := c.Get(key)
+	if !ok {
+		return false, false
+	}
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// normalizeClaims strips the `Engine.ClaimNamespace` prefix from the claim keys of the verified token.
+// Claims already present under the stripped key are kept, so namespaced claims can't shadow them.
+func (e *Engine) normalizeClaims(data any) {
+	if e.ClaimNamespace == "" {
+		return
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil || token.Claims == nil {
+		return
+	}
+	claims := make(map[string]any, len(token.Claims))
+	for k, v := range token.Claims {
+		claims[k] = v
+	}
+	for k, v := range token.Claims {
+		key, ok := strings.CutPrefix(k, e.ClaimNamespace)
+		if !ok || key == "" {
+			continue
+		}
+		delete(claims, k)
+		if _, ok := token.Claims[key]; !ok {
+			claims[key] = v
+		}
+	}
+	token.Claims = claims
+}
@@ -0,0 +1,122 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestClaimsFromContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := fauth.ClaimsFromContext(ctx); ok {
+		t.Fatal("claims shouldn't be present")
+	}
+
+	token := &auth.Token{Claims: map[string]any{"role": "admin"}}
+	c := fauth.WithAuthData(ctx, token)
+	claims, ok := fauth.ClaimsFromContext(c)
+	if !ok {
+		t.Fatal("claims should fall back to the token")
+	}
+	if role, _ := claims.String("role"); role != "admin" {
+		t.Fatalf("invalid role: %s", role)
+	}
+
+	c = fauth.WithClaims(c, fauth.Claims{"role": "editor", "admin": true})
+	claims, ok = fauth.ClaimsFromContext(c)
+	if !ok {
+		t.Fatal("claims should be present")
+	}
+	if role, _ := claims.String("role"); role != "editor" {
+		t.Fatalf("stored claims should take precedence, got role: %s", role)
+	}
+	if admin, ok := claims.Bool("admin"); !ok || !admin {
+		t.Fatal("invalid admin claim")
+	}
+	if _, ok := claims.Bool("role"); ok {
+		t.Fatal("role isn't a bool")
+	}
+}
+
+func TestClaimString(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := fauth.ClaimString(ctx, "plan"); ok {
+		t.Fatal("plan shouldn't be present")
+	}
+
+	ctx = fauth.WithClaims(ctx, fauth.Claims{"plan": "pro", "seats": 5.0})
+	if plan, ok := fauth.ClaimString(ctx, "plan"); !ok || plan != "pro" {
+		t.Fatalf("invalid plan: %s", plan)
+	}
+	if _, ok := fauth.ClaimString(ctx, "seats"); ok {
+		t.Fatal("seats isn't a string")
+	}
+	if _, ok := fauth.ClaimString(ctx, "missing"); ok {
+		t.Fatal("missing shouldn't be present")
+	}
+}
+
+func TestClaimNamespace(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.ClaimNamespace = "https://app.com/"
+	})
+	var claims fauth.Claims
+	h := withFirebaseAuth(fauth.RequireAnyRole("admin")(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = fauth.ClaimsFromContext(r.Context())
+	}))
+	w := serveBearer(h, sign(map[string]any{
+		"sub":                   "uid",
+		"https://app.com/roles": []any{"admin"},
+		"https://app.com/plan":  "pro",
+		"plan":                  "free",
+		"https://other.com/org": "acme",
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("the namespaced roles should be normalized, got: %d", w.Code)
+	}
+	if _, ok := claims["https://app.com/roles"]; ok {
+		t.Fatal("the namespaced key should be stripped")
+	}
+	if plan, _ := claims.String("plan"); plan != "free" {
+		t.Fatalf("the existing claim should take precedence, got: %s", plan)
+	}
+	if org, _ := claims.String("https://other.com/org"); org != "acme" {
+		t.Fatal("claims of other namespaces should be kept")
+	}
+}
+
+func TestDecodeClaims(t *testing.T) {
+	type appClaims struct {
+		OrgID string `json:"org_id"`
+		Role  string `json:"role"`
+		Org   struct {
+			Name  string   `json:"name"`
+			Seats int      `json:"seats"`
+			Tags  []string `json:"tags"`
+		} `json:"org"`
+	}
+	token := &auth.Token{Claims: map[string]any{
+		"org_id":         "o1",
+		"role":           "admin",
+		"org":            map[string]any{"name": "Acme", "seats": 42.0, "tags": []any{"eu", "pro"}},
+		"email_verified": true,
+	}}
+	claims, err := fauth.DecodeClaims[appClaims](token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.OrgID != "o1" || claims.Role != "admin" || claims.Org.Name != "Acme" || claims.Org.Seats != 42 ||
+		len(claims.Org.Tags) != 2 || claims.Org.Tags[1] != "pro" {
+		t.Fatalf("invalid claims: %+v", claims)
+	}
+
+	if _, err := fauth.DecodeClaims[appClaims](&auth.Token{Claims: map[string]any{"role": 1.0}}); err == nil {
+		t.Fatal("expected a type mismatch error")
+	}
+	if _, err := fauth.DecodeClaims[appClaims](nil); err == nil {
+		t.Fatal("expected an error for a nil token")
+	}
+}
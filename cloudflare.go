@@ -0,0 +1,91 @@
+package fauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth/internal/jwt"
+)
+
+// CloudflareAccessHeader is the header Cloudflare Access injects its JWT in.
+const CloudflareAccessHeader = "Cf-Access-Jwt-Assertion"
+
+// CloudflareAccess verifies the JWTs Cloudflare Access injects in the `Cf-Access-Jwt-Assertion` header
+// of the requests it lets through, making sure they went through the edge gateway.
+// Combine its `OnAuth` with the Firebase verifier using `All` to enforce both the gateway and the app identity:
+//
+//	cf := fauth.NewCloudflareAccess("https://myteam.cloudflareaccess.com", "4714c1358e65fe4b408ad6d432a5f878f08194bdb4752441fd56faefa9b2b6f2")
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnAuth = fauth.All(fauth.VerifyIDToken, cf.OnAuth)
+//	})
+//
+// The tokens are verified against the public keys of the team, fetched from its `/cdn-cgi/access/certs` endpoint
+// and cached, along with their issuer, audience and expiry.
+type CloudflareAccess struct {
+	// TeamDomain is the URL of the Cloudflare Access team, e.g. `https://myteam.cloudflareaccess.com`,
+	// which is the issuer of the tokens.
+	TeamDomain string
+	// Audience is the Application Audience (AUD) tag of the Cloudflare Access application.
+	Audience string
+	// Client fetches the public keys, defaulting to `http.DefaultClient`.
+	Client *http.Client
+
+	keys keySet
+}
+
+// NewCloudflareAccess returns a CloudflareAccess verifying the tokens of the team for the application audience.
+func NewCloudflareAccess(teamDomain, audience string) *CloudflareAccess {
+	return &CloudflareAccess{TeamDomain: strings.TrimSuffix(teamDomain, "/"), Audience: audience}
+}
+
+// OnAuth is an `Engine.OnAuth` func verifying the Cloudflare Access JWT of the request,
+// returning its `Claims`. Requests without it are rejected with `ErrNoToken`, the ones for another application
+// with 403 Forbidden and `ErrWrongAudience`.
+func (c *CloudflareAccess) OnAuth(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	raw := r.Header.Get(CloudflareAccessHeader)
+	if raw == "" {
+		return nil, fmt.Errorf("%w in header: %s", ErrNoToken, CloudflareAccessHeader)
+	}
+	t, err := jwt.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	key, err := c.keys.key(r.Context(), t.KeyID(), c.fetchKeys)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Verify(key); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(t.Payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid payload: %w", ErrInvalidToken, err)
+	}
+	if iss, _ := claims.String("iss"); iss != strings.TrimSuffix(c.TeamDomain, "/") {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidToken, iss)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if expires := time.Unix(int64(exp), 0); !Now(r.Context()).Before(expires) {
+		return nil, fmt.Errorf("%w at: %d", ErrTokenExpired, int64(exp))
+	}
+	for _, aud := range audiences(&auth.Token{Claims: claims}) {
+		if aud == c.Audience {
+			return claims, nil
+		}
+	}
+	return nil, forbidden(fmt.Errorf("%w: expected %s", ErrWrongAudience, c.Audience))
+}
+
+func (c *CloudflareAccess) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	return fetchJWKS(ctx, c.Client, strings.TrimSuffix(c.TeamDomain, "/")+"/cdn-cgi/access/certs", "Cloudflare Access")
+}
@@ -0,0 +1,158 @@
+package fauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/internal/jwt"
+)
+
+func TestCloudflareAccess(t *testing.T) {
+	cfKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cdn-cgi/access/certs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fetches++
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwt.JWK("cf", &cfKey.PublicKey)}})
+	}))
+	t.Cleanup(srv.Close)
+
+	cf := fauth.NewCloudflareAccess(srv.URL, "app-aud")
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.OnAuth = fauth.All(e.OnAuth, cf.OnAuth)
+	})
+	var uid string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		uid = token.UID
+	})
+
+	cfToken := func(kid string, claims map[string]any, key *rsa.PrivateKey) string {
+		c := map[string]any{"iss": srv.URL, "aud": []string{"app-aud"}, "sub": "cf", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range claims {
+			c[k] = v
+		}
+		s, err := jwt.Sign(map[string]any{"kid": kid}, c, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	firebaseToken := sign(map[string]any{"sub": "uid"})
+	tests := []struct {
+		firebase string
+		cf       string
+		code     int
+		err      string
+	}{
+		{firebaseToken, cfToken("cf", nil, cfKey), http.StatusOK, ""},
+		{firebaseToken, "", http.StatusUnauthorized, "no_token"},
+		{"", cfToken("cf", nil, cfKey), http.StatusUnauthorized, "no_token"},
+		{firebaseToken, cfToken("cf", map[string]any{"aud": []string{"other"}}, cfKey), http.StatusForbidden, "wrong_audience"},
+		{firebaseToken, cfToken("cf", map[string]any{"iss": "https://evil.cloudflareaccess.com"}, cfKey), http.StatusUnauthorized, "invalid_token"},
+		{firebaseToken, cfToken("cf", map[string]any{"exp": time.Now().Add(-time.Minute).Unix()}, cfKey), http.StatusUnauthorized, "expired"},
+		{firebaseToken, cfToken("cf", nil, testKey), http.StatusUnauthorized, "invalid_token"},
+		{firebaseToken, cfToken("unknown", nil, cfKey), http.StatusUnauthorized, "invalid_token"},
+		{firebaseToken, "garbage", http.StatusUnauthorized, "invalid_token"},
+	}
+	for i, tt := range tests {
+		uid = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		if tt.firebase != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.firebase)
+		}
+		if tt.cf != "" {
+			r.Header.Set(fauth.CloudflareAccessHeader, tt.cf)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+		if tt.code == http.StatusOK && uid != "uid" {
+			t.Fatalf("%d: the data of the Firebase verifier should be kept, got %q", i, uid)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("the keys should be cached, got %d fetches", fetches)
+	}
+
+	srv.Close()
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	r.Header.Set(fauth.CloudflareAccessHeader, cfToken("cf", nil, cfKey))
+	_, err = fauth.NewCloudflareAccess(srv.URL, "app-aud").OnAuth(r, nil, nil)
+	var se fauth.StatusError
+	if !errors.As(err, &se) || se.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %v", err)
+	}
+}
+
+func TestCloudflareAccessOutage(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	now := time.Now()
+	cf := fauth.NewCloudflareAccess(srv.URL, "app-aud")
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.OnAuth = cf.OnAuth
+		e.Now = func() time.Time {
+			return now
+		}
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	token, err := jwt.Sign(map[string]any{"kid": "cf"}, map[string]any{"iss": srv.URL}, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r.Header.Set(fauth.CloudflareAccessHeader, token)
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", w.Code)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve()
+		}()
+	}
+	wg.Wait()
+	serve()
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("the failed fetches should be rate-limited, got %d fetches", n)
+	}
+	now = now.Add(2 * time.Minute)
+	serve()
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("the keys should be refetched after a while, got %d fetches", n)
+	}
+}
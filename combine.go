@@ -0,0 +1,36 @@
+package fauth
+
+import (
+	"net/http"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// All returns an `Engine.OnAuth` func requiring all the verifiers to accept the request, e.g. the Firebase ID token
+// along with the Cloudflare Access JWT, see `CloudflareAccess`. It returns the data of the first verifier,
+// the other ones acting as additional gates. It panics if no verifier is given.
+//
+// The verifiers run in the given order, one at a time, stopping at the first failure, whose error is returned as is,
+// keeping its type and code, see `ErrorCode`. This order is stable, so put first the verifier whose errors
+// the clients should see first, e.g. the ID token ones before the App Check ones:
+//
+//	e.OnAuth = fauth.All(fauth.VerifyIDToken, fauth.VerifyAppCheckToken(""))
+func All(verifiers ...OnAuthFunc) OnAuthFunc {
+	if len(verifiers) == 0 {
+		panic("fauth: All needs at least one verifier")
+	}
+	return func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+		var first any
+		for i, verify := range verifiers {
+			data, err := verify(r, app, client)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				first = data
+			}
+		}
+		return first, nil
+	}
+}
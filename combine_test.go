@@ -0,0 +1,51 @@
+package fauth_test
+
+import (
+	"net/http"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestAllOrder(t *testing.T) {
+	var calls []string
+	verifier := func(name string, err error) fauth.OnAuthFunc {
+		return func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			calls = append(calls, name)
+			if err != nil {
+				return nil, err
+			}
+			return &auth.Token{UID: name}, nil
+		}
+	}
+	tests := []struct {
+		verifiers []fauth.OnAuthFunc
+		calls     []string
+		err       string
+	}{
+		{[]fauth.OnAuthFunc{verifier("a", nil), verifier("b", nil)}, []string{"a", "b"}, ""},
+		{[]fauth.OnAuthFunc{verifier("token", fauth.ErrTokenExpired), verifier("app", fauth.ErrNoAppCheckToken)}, []string{"token"}, "expired"},
+		{[]fauth.OnAuthFunc{verifier("app", fauth.ErrNoAppCheckToken), verifier("token", fauth.ErrTokenExpired)}, []string{"app"}, "no_app_check_token"},
+		{[]fauth.OnAuthFunc{verifier("a", nil), verifier("b", fauth.ErrWrongAudience), verifier("c", fauth.ErrTokenRevoked)}, []string{"a", "b"}, "wrong_audience"},
+	}
+	for i, tt := range tests {
+		calls = nil
+		data, err := fauth.All(tt.verifiers...)(nil, nil, nil)
+		if code := fauth.ErrorCode(err); code != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, code)
+		}
+		if len(calls) != len(tt.calls) {
+			t.Fatalf("%d: expected %v, got %v", i, tt.calls, calls)
+		}
+		for j := range calls {
+			if calls[j] != tt.calls[j] {
+				t.Fatalf("%d: expected %v, got %v", i, tt.calls, calls)
+			}
+		}
+		if err == nil && data.(*auth.Token).UID != "a" {
+			t.Fatalf("%d: the data of the first verifier should be returned", i)
+		}
+	}
+}
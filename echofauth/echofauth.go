@@ -0,0 +1,80 @@
+// Package echofauth adapts the fauth middleware to the Echo framework.
+//
+// It's a separate module, so the fauth package doesn't depend on Echo.
+package echofauth
+
+import (
+	"context"
+	"net/http"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/labstack/echo/v4"
+)
+
+// DataKey is the key the auth data, e.g. the `*auth.Token`, is stored under in the `echo.Context`.
+const DataKey = "fauth.data"
+
+// Middleware returns an Echo middleware func verifying the request is coming from a valid Firebase user,
+// configured like `fauth.Auth`:
+//
+//	withFirebaseAuth, err := echofauth.Middleware(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	e := echo.New()
+//	e.GET("/private", handler, withFirebaseAuth)
+//
+// The auth data is stored in the `echo.Context` under the `DataKey`, see `Token`, and, for compatibility,
+// in the request context, where the fauth funcs, e.g. `fauth.AuthToken`, read it from.
+// Failures are returned as `*echo.HTTPError`s, rendered by the Echo error handler, with the status code of the error,
+// see `fauth.StatusCode`, and its code, see `fauth.ErrorCode`, as the message; the error itself is kept internal.
+// The `fauth.Engine` fields writing the response are honored, e.g. the `HealthPath` or the `WWW-Authenticate`
+// challenge, see `fauth.Authenticator.AuthenticateHTTP`.
+func Middleware(ctx context.Context, opts ...fauth.Option) (echo.MiddlewareFunc, error) {
+	a, err := fauth.NewAuthenticator(ctx, append([]fauth.Option{fauth.WithoutClose("echofauth.Middleware")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return MiddlewareFor(a), nil
+}
+
+// MiddlewareFor is like `Middleware`, but uses the Authenticator, e.g. to share it with other handlers
+// or to reload its credentials.
+func MiddlewareFor(a *fauth.Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r, err := a.AuthenticateHTTP(c.Response(), c.Request())
+			if err != nil {
+				return HTTPError(err)
+			}
+			if r == nil {
+				return nil
+			}
+			c.SetRequest(r)
+			if data := fauth.AuthData(r.Context()); data != nil {
+				c.Set(DataKey, data)
+			}
+			return next(c)
+		}
+	}
+}
+
+// Token returns the verified token stored in the `echo.Context` by the middleware.
+func Token(c echo.Context) (*auth.Token, bool) {
+	if token, ok := fauth.AuthToken(c.Request().Context()); ok && token != nil {
+		return token, true
+	}
+	token, ok := c.Get(DataKey).(*auth.Token)
+	return token, ok && token != nil
+}
+
+// HTTPError converts the fauth error to an `*echo.HTTPError`, keeping the error internal.
+func HTTPError(err error) *echo.HTTPError {
+	code := fauth.StatusCode(err)
+	msg := fauth.ErrorCode(err)
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	return echo.NewHTTPError(code, msg).SetInternal(err)
+}
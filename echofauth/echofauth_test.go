@@ -0,0 +1,110 @@
+package echofauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/echofauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFirebaseAuth, err := echofauth.Middleware(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.GET("/private", func(c echo.Context) error {
+		token, ok := echofauth.Token(c)
+		if !ok {
+			return errors.New("missing token")
+		}
+		if t, _ := fauth.AuthToken(c.Request().Context()); t != token {
+			return errors.New("the request context should carry the token")
+		}
+		return c.String(http.StatusOK, token.UID)
+	}, withFirebaseAuth)
+
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		header string
+		code   int
+		body   string
+	}{
+		{"Bearer " + jwt, http.StatusOK, "uid"},
+		{"Bearer garbage", http.StatusUnauthorized, `{"message":"invalid_token"}` + "\n"},
+		{"", http.StatusUnauthorized, `{"message":"no_token"}` + "\n"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/private", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		e.ServeHTTP(w, r)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Fatalf("%d: expected %d %q, got %d %q", i, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMiddlewareResponse(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFirebaseAuth, err := echofauth.Middleware(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option(), func(e *fauth.Engine) {
+		e.HealthPath = "/healthz"
+		e.Realm = "api"
+		e.PrivateCacheControl = true
+		e.Skip = fauth.SkipPaths("/public")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Use(withFirebaseAuth)
+	e.GET("/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, "handled")
+	})
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path, header string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		e.ServeHTTP(w, r)
+		return w
+	}
+	if w := serve("/healthz", ""); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("the health path should be answered, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve("/public", ""); w.Code != http.StatusOK || w.Body.String() != "handled" {
+		t.Fatalf("the skipped paths should be let through, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve("/private", ""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+		t.Fatalf("the 401 responses should be challenged, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if w := serve("/private", "Bearer "+jwt); w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "private, no-store" {
+		t.Fatalf("the authenticated responses should be private, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,146 @@
+package fauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"google.golang.org/api/option"
+)
+
+// The environment variables recognized by `AuthFromEnv`.
+const (
+	// EnvProjectID sets the ID of the Firebase project, overriding the one of the credentials.
+	EnvProjectID = "FIREBASE_PROJECT_ID"
+	// EnvCredentialsJSON sets the JSON-encoded service account credentials.
+	EnvCredentialsJSON = "FIREBASE_CREDENTIALS_JSON"
+	// EnvCredentialsFile sets the path of the service account credentials file.
+	// It's ignored when `FIREBASE_CREDENTIALS_JSON` is set.
+	EnvCredentialsFile = "FIREBASE_CREDENTIALS_FILE"
+	// EnvCheckRevoked, if true, makes the middleware check the tokens haven't been revoked, see `VerifyIDTokenAndCheckRevoked`.
+	EnvCheckRevoked = "FAUTH_CHECK_REVOKED"
+	// EnvEmulatorHost points the Auth client to the Firebase Auth Emulator, e.g. `localhost:9099`.
+	EnvEmulatorHost = "FAUTH_EMULATOR_HOST"
+	// EnvRealm sets `Engine.Realm`.
+	EnvRealm = "FAUTH_REALM"
+	// EnvAuthScheme sets `Engine.AuthScheme`.
+	EnvAuthScheme = "FAUTH_AUTH_SCHEME"
+	// EnvHealthPath sets `Engine.HealthPath`.
+	EnvHealthPath = "FAUTH_HEALTH_PATH"
+)
+
+// emulatorHostEnv is the environment variable the Firebase Admin SDK reads the Auth Emulator host from.
+const emulatorHostEnv = "FIREBASE_AUTH_EMULATOR_HOST"
+
+// emulatorMu serializes the creation of the Auth clients pointed to an emulator by `authClient`.
+var emulatorMu sync.Mutex
+
+// authClient creates the Auth client of the app, pointed to the Auth Emulator at the host, if any.
+// The Firebase Admin SDK only reads the host from the `FIREBASE_AUTH_EMULATOR_HOST` variable, and only when creating
+// the client, so the variable is set for the creation and restored right after, keeping it from leaking
+// to the other clients of the process.
+func authClient(ctx context.Context, app *firebase.App, host string) (*auth.Client, error) {
+	if host == "" {
+		return app.Auth(ctx)
+	}
+	emulatorMu.Lock()
+	defer emulatorMu.Unlock()
+	prev, ok := os.LookupEnv(emulatorHostEnv)
+	if err := os.Setenv(emulatorHostEnv, host); err != nil {
+		return nil, fmt.Errorf("failed to set %s: %w", emulatorHostEnv, err)
+	}
+	defer func() {
+		if ok {
+			_ = os.Setenv(emulatorHostEnv, prev)
+		} else {
+			_ = os.Unsetenv(emulatorHostEnv)
+		}
+	}()
+	return app.Auth(ctx)
+}
+
+// AuthFromEnv is like `Auth`, but it configures the engine from the environment variables,
+// removing the wiring boilerplate of twelve-factor deployments:
+//
+//   - `FIREBASE_PROJECT_ID` sets the ID of the Firebase project.
+//   - `FIREBASE_CREDENTIALS_JSON` sets the JSON-encoded service account credentials.
+//   - `FIREBASE_CREDENTIALS_FILE` sets the path of the service account credentials file.
+//     Without either, the Application Default Credentials are used.
+//   - `FAUTH_CHECK_REVOKED`, if true, checks the tokens haven't been revoked using `VerifyIDTokenAndCheckRevoked`.
+//   - `FAUTH_EMULATOR_HOST` points the Auth client of the engine to the Firebase Auth Emulator, leaving
+//     the other clients of the process alone, unlike the `FIREBASE_AUTH_EMULATOR_HOST` variable.
+//   - `FAUTH_REALM`, `FAUTH_AUTH_SCHEME` and `FAUTH_HEALTH_PATH` set the `Engine` fields of the same name.
+//
+// The unset variables leave the `Engine` alone, e.g. to the defaults registered with `SetDefaults`,
+// while the given options are applied after the ones derived from the environment, so they take precedence.
+func AuthFromEnv(ctx context.Context, opts ...Option) (func(http.HandlerFunc) http.HandlerFunc, error) {
+	envOpts, err := optionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return Auth(ctx, append(append(envOpts, WithoutClose("AuthFromEnv")), opts...)...)
+}
+
+func optionsFromEnv() ([]Option, error) {
+	var opts []Option
+	if host := os.Getenv(EnvEmulatorHost); host != "" {
+		opts = append(opts, func(e *Engine) {
+			e.emulatorHost = host
+		})
+	}
+	if v := os.Getenv(EnvCheckRevoked); v != "" {
+		checkRevoked, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("fauth: invalid %s: %w", EnvCheckRevoked, err)
+		}
+		if checkRevoked {
+			opts = append(opts, func(e *Engine) {
+				e.OnAuth = VerifyIDTokenAndCheckRevoked
+			})
+		}
+	}
+
+	// The unset variables leave the fields alone, e.g. to the defaults registered with `SetDefaults`.
+	var config *firebase.Config
+	if projectID := os.Getenv(EnvProjectID); projectID != "" {
+		config = &firebase.Config{ProjectID: projectID}
+	}
+	var appOpts []option.ClientOption
+	if creds := os.Getenv(EnvCredentialsJSON); creds != "" {
+		appOpts = append(appOpts, option.WithCredentialsJSON([]byte(creds)))
+	} else if file := os.Getenv(EnvCredentialsFile); file != "" {
+		appOpts = append(appOpts, option.WithCredentialsFile(file))
+	}
+	if config != nil || len(appOpts) > 0 {
+		opts = append(opts, func(e *Engine) {
+			e.NewApp = func(ctx context.Context) (*firebase.App, error) {
+				app, err := firebase.NewApp(ctx, config, appOpts...)
+				if err != nil {
+					return nil, fmt.Errorf("failed to initialize Firebase app: %w", err)
+				}
+				return app, nil
+			}
+		})
+	}
+	if realm := os.Getenv(EnvRealm); realm != "" {
+		opts = append(opts, func(e *Engine) {
+			e.Realm = realm
+		})
+	}
+	if scheme := os.Getenv(EnvAuthScheme); scheme != "" {
+		opts = append(opts, func(e *Engine) {
+			e.AuthScheme = scheme
+		})
+	}
+	if healthPath := os.Getenv(EnvHealthPath); healthPath != "" {
+		opts = append(opts, func(e *Engine) {
+			e.HealthPath = healthPath
+		})
+	}
+	return opts, nil
+}
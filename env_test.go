@@ -0,0 +1,91 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestAuthFromEnv(t *testing.T) {
+	fakeEmulator(t, map[string]string{"accounts:lookup": `{"users":[{"localId":"uid","validSince":"0"}]}`})
+	// Only the engine is pointed to the emulator, not the process.
+	t.Setenv(fauth.EnvEmulatorHost, os.Getenv("FIREBASE_AUTH_EMULATOR_HOST"))
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", "")
+	t.Setenv(fauth.EnvProjectID, "fauthtest")
+	t.Setenv(fauth.EnvRealm, "api")
+	t.Setenv(fauth.EnvHealthPath, "/healthz")
+
+	withFirebaseAuth, err := fauth.AuthFromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("invalid health status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com/private", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+	if c := w.Header().Get("WWW-Authenticate"); c != `Bearer realm="api"` {
+		t.Fatalf("invalid challenge: %s", c)
+	}
+
+	if w := serveBearer(h, fauthtest.EmulatorToken("uid")); w.Code != http.StatusOK {
+		t.Fatalf("the emulator should verify the token, got: %d", w.Code)
+	}
+	if host := os.Getenv("FIREBASE_AUTH_EMULATOR_HOST"); host != "" {
+		t.Fatalf("the environment shouldn't be modified, got: %s", host)
+	}
+}
+
+func TestAuthFromEnvInvalid(t *testing.T) {
+	t.Setenv(fauth.EnvCheckRevoked, "sometimes")
+	if _, err := fauth.AuthFromEnv(context.Background()); err == nil {
+		t.Fatal("invalid FAUTH_CHECK_REVOKED should fail")
+	}
+}
+
+func TestAuthFromEnvDefaults(t *testing.T) {
+	fakeEmulator(t, nil)
+	for _, env := range []string{fauth.EnvProjectID, fauth.EnvCredentialsJSON, fauth.EnvCredentialsFile, fauth.EnvHealthPath} {
+		t.Setenv(env, "")
+	}
+	t.Setenv(fauth.EnvRealm, "api")
+	fauth.SetDefaults(func(e *fauth.Engine) {
+		e.NewApp = func(ctx context.Context) (*firebase.App, error) {
+			return firebase.NewApp(ctx, &firebase.Config{ProjectID: "fauthtest"})
+		}
+		e.Realm = "fromdefaults"
+		e.HealthPath = "/hz"
+	})
+	defer fauth.SetDefaults()
+
+	withFirebaseAuth, err := fauth.AuthFromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com/hz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("the unset variables should keep the defaults, got: %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com/private", nil))
+	if c := w.Header().Get("WWW-Authenticate"); c != `Bearer realm="api"` {
+		t.Fatalf("the set variables should override the defaults, got: %s", c)
+	}
+}
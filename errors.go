@@ -0,0 +1,294 @@
+package fauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// ErrUnauthenticated is matched by the errors answered with 401 Unauthorized by default, i.e. the authentication errors
+// below and the verification failures, telling them apart from the `ErrForbidden` ones in a custom `Engine.OnErr`:
+//
+//	if errors.Is(err, fauth.ErrUnauthenticated) {
+//		http.Redirect(w, r, "/login", http.StatusFound)
+//	}
+var ErrUnauthenticated = errors.New("fauth: unauthenticated")
+
+// ErrForbidden is matched by the errors answered with 403 Forbidden by default, i.e. the ones of the requests
+// that are authenticated, but not permitted, e.g. by the `Require*` middleware funcs.
+// Custom hooks can wrap it to get the same treatment, e.g. `fmt.Errorf("%w: banned", fauth.ErrForbidden)`.
+var ErrForbidden = errors.New("fauth: forbidden")
+
+// Authentication errors, answered with 401 Unauthorized by default. They all match `ErrUnauthenticated`.
+var (
+	// ErrNoToken is returned when the request doesn't carry a token.
+	ErrNoToken = authError("fauth: missing token")
+	// ErrMalformedHeader is returned when the header carrying the token can't be parsed.
+	ErrMalformedHeader = authError("fauth: invalid header")
+	// ErrInvalidToken is returned when the token fails the verification, e.g. due to an invalid signature.
+	ErrInvalidToken = authError("fauth: invalid token")
+	// ErrTokenExpired is returned when the token has expired.
+	ErrTokenExpired = authError("fauth: token expired")
+	// ErrTokenRevoked is returned when the token has been revoked.
+	ErrTokenRevoked = authError("fauth: token revoked")
+	// ErrUserDisabled is returned when the user the token was issued to has been disabled.
+	ErrUserDisabled = authError("fauth: user disabled")
+)
+
+// unauthenticatedError is an authentication error, matching `ErrUnauthenticated`.
+type unauthenticatedError struct {
+	msg string
+}
+
+func authError(msg string) error {
+	return &unauthenticatedError{msg: msg}
+}
+
+func (e *unauthenticatedError) Error() string {
+	return e.msg
+}
+
+func (e *unauthenticatedError) Is(target error) bool {
+	return target == ErrUnauthenticated
+}
+
+func (e *unauthenticatedError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+// ErrWrongProject is returned when the token was issued for another Firebase project.
+var ErrWrongProject = errors.New("fauth: wrong project")
+
+// ErrMissingClaim is returned when a claim required by one of the `Require*` middlewares is absent.
+var ErrMissingClaim = errors.New("fauth: missing claim")
+
+// ErrEmailNotVerified is returned when the email of the token hasn't been verified.
+var ErrEmailNotVerified = errors.New("fauth: email not verified")
+
+// ErrClaimMismatch is returned when a claim required by one of the `Require*` middlewares has an unexpected value.
+var ErrClaimMismatch = errors.New("fauth: claim mismatch")
+
+// ErrMissingRole is returned when the user lacks the roles required by `RequireAnyRole` or `RequireAllRoles`.
+var ErrMissingRole = errors.New("fauth: missing role")
+
+// ErrMissingPermission is returned when the user lacks the permission required by `RequirePermission`.
+var ErrMissingPermission = errors.New("fauth: missing permission")
+
+// ErrAccountTooNew is returned when the account of the user is younger than required by `RequireAccountAge`.
+var ErrAccountTooNew = errors.New("fauth: account too new")
+
+// ErrWrongAudience is returned when the token wasn't issued for the expected audience.
+var ErrWrongAudience = errors.New("fauth: wrong audience")
+
+// ErrUIDNotAllowed is returned when the UID of the token isn't allowed by `RequireUIDFunc`.
+var ErrUIDNotAllowed = errors.New("fauth: uid not allowed")
+
+// ErrPlatformNotAllowed is returned when the platform of the token isn't allowed by `RequirePlatform`.
+var ErrPlatformNotAllowed = errors.New("fauth: platform not allowed")
+
+// ErrRateLimited is returned when the requests exceed the limit set by `RateLimitBy` or `RateLimitByUID`.
+var ErrRateLimited = errors.New("fauth: rate limited")
+
+// ErrVerifierUnavailable is returned when the token can't be verified because the verifier is unavailable,
+// and it wasn't verified before, see `Engine.ServeStaleTokens`.
+var ErrVerifierUnavailable = errors.New("fauth: verifier unavailable")
+
+// ErrNoAppCheckToken is returned when the request doesn't carry an App Check token, see `AppCheck`.
+var ErrNoAppCheckToken = errors.New("fauth: missing App Check token")
+
+// ErrInvalidAppCheckToken is returned when the App Check token fails the verification, or has expired, see `AppCheck`.
+var ErrInvalidAppCheckToken = errors.New("fauth: invalid App Check token")
+
+// ErrTimeout is returned when the verification times out, see `Engine.VerifyTimeout` and `Engine.DefaultTimeout`.
+var ErrTimeout = errors.New("fauth: verification timed out")
+
+// ErrTokenReused is returned when a single-use token is used again, see `RequireOneTimeUse`.
+var ErrTokenReused = errors.New("fauth: token reused")
+
+// ErrIPNotAllowed is returned when the IP address of the client isn't allowed by `RequireIPAllowed`.
+var ErrIPNotAllowed = errors.New("fauth: ip not allowed")
+
+// ErrTOSNotAccepted is returned when the user hasn't accepted the current terms of service, see `RequireTOSVersion`.
+var ErrTOSNotAccepted = errors.New("fauth: terms of service not accepted")
+
+var errNoAuthToken = fmt.Errorf("%w in the request context", ErrNoToken)
+
+// errorCodes maps the errors to stable, machine-readable codes.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrNoToken, "no_token"},
+	{ErrMalformedHeader, "malformed"},
+	{ErrTokenExpired, "expired"},
+	{ErrTokenRevoked, "revoked"},
+	{ErrUserDisabled, "disabled"},
+	{ErrInvalidToken, "invalid_token"},
+	{ErrWrongProject, "wrong_project"},
+	{ErrWrongAudience, "wrong_audience"},
+	{ErrMissingClaim, "missing_claim"},
+	{ErrClaimMismatch, "claim_mismatch"},
+	{ErrEmailNotVerified, "email_not_verified"},
+	{ErrMissingRole, "missing_role"},
+	{ErrMissingPermission, "missing_permission"},
+	{ErrAccountTooNew, "account_too_new"},
+	{ErrUIDNotAllowed, "uid_not_allowed"},
+	{ErrPlatformNotAllowed, "platform_not_allowed"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrBodyTooLarge, "body_too_large"},
+	{ErrBindingMismatch, "binding_mismatch"},
+	{ErrNoTenant, "no_tenant"},
+	{ErrVerifierUnavailable, "verifier_unavailable"},
+	{ErrNoAppCheckToken, "no_app_check_token"},
+	{ErrInvalidAppCheckToken, "invalid_app_check_token"},
+	{ErrTimeout, "timeout"},
+	{ErrTokenReused, "token_reused"},
+	{ErrIPNotAllowed, "ip_not_allowed"},
+	{ErrTOSNotAccepted, "tos_not_accepted"},
+}
+
+// ErrorCode returns a stable, machine-readable code describing the error, e.g. `expired`,
+// or an empty string if the error isn't one of the fauth errors.
+// The default `Engine.OnErr` sends it to the client in the `X-Auth-Error` header, letting clients
+// map failures to localized messages while keeping the human-readable errors server-side.
+//
+// The codes are `no_token`, `malformed`, `expired`, `revoked`, `disabled`, `invalid_token`, `wrong_project`,
+// `wrong_audience`, `missing_claim`, `claim_mismatch`, `email_not_verified`, `missing_role`, `missing_permission`,
+// `account_too_new`, `uid_not_allowed`, `platform_not_allowed`, `rate_limited`, `body_too_large`, `binding_mismatch`,
+// `no_tenant`, `verifier_unavailable`, `no_app_check_token`, `invalid_app_check_token`, `timeout`, `token_reused`,
+// `ip_not_allowed` and `tos_not_accepted`.
+func ErrorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// errorReason returns a short, human-readable reason for the error, e.g. `token expired`,
+// falling back to the status text of the code for the errors that aren't one of the fauth errors.
+func errorReason(err error, code int) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return strings.TrimPrefix(c.err.Error(), "fauth: ")
+		}
+	}
+	return http.StatusText(code)
+}
+
+// WriteJSONError responds with the status code and a JSON payload describing the error, e.g.:
+//
+//	{"error":"unauthorized","code":"expired","message":"token expired"}
+//
+// The `error` is the status text in snake case, the `code` is the stable code of the error, see `ErrorCode`,
+// omitted for the errors that aren't one of the fauth errors, and the `message` is a short, human-readable reason
+// that doesn't leak the details of the failure. It's used by the default `Engine.OnErr` when `Engine.JSONErrors` is set,
+// and is handy in custom ones.
+func WriteJSONError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error   string `json:"error"`
+		Code    string `json:"code,omitempty"`
+		Message string `json:"message"`
+	}{
+		Error:   strings.ReplaceAll(strings.ToLower(http.StatusText(code)), " ", "_"),
+		Code:    ErrorCode(err),
+		Message: errorReason(err, code),
+	})
+}
+
+// verifyError maps the error returned by the Firebase Admin SDK to the fauth errors.
+func verifyError(err error) error {
+	var se StatusError
+	switch {
+	case errors.As(err, &se):
+		// Already a fauth error, e.g. returned by a fake `Engine.Verifier`.
+		return err
+	case auth.IsIDTokenExpired(err), auth.IsSessionCookieExpired(err):
+		return fmt.Errorf("%w: %w", ErrTokenExpired, err)
+	case auth.IsIDTokenRevoked(err), auth.IsSessionCookieRevoked(err):
+		return fmt.Errorf("%w: %w", ErrTokenRevoked, err)
+	case auth.IsUserDisabled(err):
+		return fmt.Errorf("%w: %w", ErrUserDisabled, err)
+	case auth.IsIDTokenInvalid(err), auth.IsSessionCookieInvalid(err), auth.IsTenantIDMismatch(err), auth.IsUserNotFound(err):
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return fmt.Errorf("%w: failed to verify the token: %w", ErrUnauthenticated, err)
+}
+
+// StatusError is implemented by the errors carrying the HTTP status code the default `Engine.OnErr` responds with,
+// i.e. the fauth errors, simplifying custom `Engine.OnErr` funcs:
+//
+//	var se fauth.StatusError
+//	if errors.As(err, &se) {
+//		w.WriteHeader(se.StatusCode())
+//	}
+//
+// The errors of custom hooks implementing it are answered with their status code as well.
+// As the fauth errors are often wrapped, prefer `errors.As` to a type assertion; `errors.Is` keeps working alongside.
+type StatusError interface {
+	error
+	StatusCode() int
+}
+
+// statusError annotates an error with the HTTP status code the default `Engine.OnErr` responds with.
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+func (e *statusError) StatusCode() int {
+	return e.code
+}
+
+// Is matches the status code with `ErrUnauthenticated` or `ErrForbidden`.
+func (e *statusError) Is(target error) bool {
+	return target == ErrUnauthenticated && e.code == http.StatusUnauthorized ||
+		target == ErrForbidden && e.code == http.StatusForbidden
+}
+
+func forbidden(err error) error {
+	return &statusError{code: http.StatusForbidden, err: err}
+}
+
+func unavailable(err error) error {
+	return &statusError{code: http.StatusServiceUnavailable, err: err}
+}
+
+// withStatus annotates the error with the status code, unless it's already annotated.
+func withStatus(code int, err error) error {
+	var se StatusError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &statusError{code: code, err: err}
+}
+
+// StatusCode returns the HTTP status code the default `Engine.OnErr` answers the error with, before applying
+// the `Engine.UnauthorizedStatus`: the one of its `StatusError` if any, 403 Forbidden for the errors wrapping
+// `ErrForbidden` and 401 Unauthorized otherwise. It's meant for the adapters to other frameworks and transports.
+func StatusCode(err error) int {
+	var se StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode()
+	}
+	if errors.Is(err, ErrForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
@@ -0,0 +1,177 @@
+package fauth_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestErrorCode(t *testing.T) {
+	if code := fauth.ErrorCode(errors.New("unknown")); code != "" {
+		t.Fatalf("unknown errors shouldn't have a code: %s", code)
+	}
+	if code := fauth.ErrorCode(fmt.Errorf("wrapped: %w", fauth.ErrTokenRevoked)); code != "revoked" {
+		t.Fatalf("invalid code: %s", code)
+	}
+
+	withFirebaseAuth, sign := offlineAuth(t)
+	h := withFirebaseAuth(fauth.RequireClaimPresent("tenant")(func(w http.ResponseWriter, r *http.Request) {}))
+
+	expired := time.Now().Add(-time.Minute).Unix()
+	tests := []struct {
+		header string
+		status int
+		code   string
+	}{
+		{"", http.StatusUnauthorized, "no_token"},
+		{"Basic dXNlcg==", http.StatusUnauthorized, "malformed"},
+		{"Bearer garbage", http.StatusUnauthorized, "invalid_token"},
+		{"Bearer " + sign(map[string]any{"sub": "uid", "exp": expired}), http.StatusUnauthorized, "expired"},
+		{"Bearer " + sign(map[string]any{"sub": "uid"}), http.StatusForbidden, "missing_claim"},
+		{"Bearer " + sign(map[string]any{"sub": "uid", "tenant": "acme"}), http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("Authorization", tt.header)
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d", tt.code, tt.status, w.Code)
+		}
+		if code := w.Header().Get("X-Auth-Error"); code != tt.code {
+			t.Fatalf("expected %q, got %q", tt.code, code)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.JSONErrors = true
+		e.UnauthorizedStatus = http.StatusNotFound
+	})
+	h := withFirebaseAuth(fauth.RequireClaimPresent("tenant")(func(w http.ResponseWriter, r *http.Request) {}))
+	expired := time.Now().Add(-time.Minute).Unix()
+	tests := []struct {
+		jwt    string
+		status int
+		body   string
+	}{
+		{sign(map[string]any{"sub": "uid", "exp": expired}), http.StatusNotFound,
+			`{"error":"not_found","code":"expired","message":"token expired"}`},
+		{sign(map[string]any{"sub": "uid"}), http.StatusForbidden,
+			`{"error":"forbidden","code":"missing_claim","message":"missing claim"}`},
+	}
+	for _, tt := range tests {
+		w := serveBearer(h, tt.jwt)
+		if w.Code != tt.status {
+			t.Fatalf("expected %d, got %d", tt.status, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != tt.body {
+			t.Fatalf("expected %s, got %s", tt.body, body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("invalid content type: %s", ct)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	fauth.WriteJSONError(w, http.StatusServiceUnavailable, errors.New("db is down"))
+	if body := strings.TrimSpace(w.Body.String()); body != `{"error":"service_unavailable","message":"Service Unavailable"}` {
+		t.Fatalf("unknown errors shouldn't leak details: %s", body)
+	}
+}
+
+func TestErrUnauthenticatedAndForbidden(t *testing.T) {
+	onData := func(e *fauth.Engine) {
+		e.OnData = func(r *http.Request, data any) (*http.Request, error) {
+			if token, _ := data.(*auth.Token); token.UID == "banned" {
+				return nil, fmt.Errorf("%w: banned", fauth.ErrForbidden)
+			}
+			return r.WithContext(fauth.WithAuthData(r.Context(), data)), nil
+		}
+	}
+	var got error
+	withFirebaseAuth, sign := offlineAuth(t, onData)
+	withRecordedErr, _ := offlineAuth(t, onData, func(e *fauth.Engine) {
+		e.OnErr = func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+			got = err
+		}
+	})
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	withTenant := fauth.RequireClaimPresent("tenant")
+
+	tests := []struct {
+		jwt       string
+		status    int
+		forbidden bool
+	}{
+		{"", http.StatusUnauthorized, false},
+		{"garbage", http.StatusUnauthorized, false},
+		{sign(map[string]any{"sub": "uid", "exp": time.Now().Add(-time.Minute).Unix()}), http.StatusUnauthorized, false},
+		{sign(map[string]any{"sub": "uid"}), http.StatusForbidden, true},
+		{sign(map[string]any{"sub": "banned", "tenant": "acme"}), http.StatusForbidden, true},
+	}
+	for i, tt := range tests {
+		if w := serveBearer(withFirebaseAuth(withTenant(h)), tt.jwt); w.Code != tt.status {
+			t.Fatalf("%d: expected %d, got %d", i, tt.status, w.Code)
+		}
+		got = nil
+		serveBearer(withRecordedErr(withTenant(h)), tt.jwt)
+		if errors.Is(got, fauth.ErrForbidden) != tt.forbidden || errors.Is(got, fauth.ErrUnauthenticated) == tt.forbidden {
+			t.Fatalf("%d: unexpected error: %v", i, got)
+		}
+	}
+}
+
+type teapotError struct{}
+
+func (teapotError) Error() string   { return "teapot" }
+func (teapotError) StatusCode() int { return http.StatusTeapot }
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{fauth.ErrNoToken, http.StatusUnauthorized},
+		{fmt.Errorf("%w: header", fauth.ErrMalformedHeader), http.StatusUnauthorized},
+		{fauth.ErrForbidden, 0},
+		{errors.New("unknown"), 0},
+	}
+	for _, tt := range tests {
+		var se fauth.StatusError
+		if ok := errors.As(tt.err, &se); ok != (tt.code != 0) || ok && se.StatusCode() != tt.code {
+			t.Fatalf("%v: expected %d, got %v", tt.err, tt.code, se)
+		}
+	}
+	if se, ok := fauth.ErrTokenExpired.(fauth.StatusError); !ok || se.StatusCode() != http.StatusUnauthorized {
+		t.Fatal("ErrTokenExpired should be a StatusError")
+	}
+
+	var got error
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.OnErr = func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+			got = err
+		}
+	})
+	serveBearer(withFirebaseAuth(fauth.RequireClaimPresent("tenant")(func(w http.ResponseWriter, r *http.Request) {})),
+		sign(map[string]any{"sub": "uid"}))
+	if se, ok := got.(fauth.StatusError); !ok || se.StatusCode() != http.StatusForbidden || !errors.Is(got, fauth.ErrMissingClaim) {
+		t.Fatalf("unexpected error: %v", got)
+	}
+
+	withTeapot, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.PreVerify = func(r *http.Request) error { return teapotError{} }
+	})
+	if w := serveBearer(withTeapot(func(w http.ResponseWriter, r *http.Request) {}), ""); w.Code != http.StatusTeapot {
+		t.Fatalf("expected 418, got %d", w.Code)
+	}
+}
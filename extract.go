@@ -0,0 +1,229 @@
+package fauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// TokenExtractor extracts the token from the request.
+type TokenExtractor func(r *http.Request) (string, error)
+
+// ErrBodyTooLarge is returned by the body extractors when the body exceeds the `Engine.MaxBodyBytes`.
+var ErrBodyTooLarge = errors.New("fauth: request body too large")
+
+const defaultMaxBodyBytes = 1 << 20
+
+// maxMultipartMemory bounds the multipart parts `FromForm` keeps in memory, the rest being stored in temporary files.
+const maxMultipartMemory = 256 << 10
+
+// FromCookie returns a TokenExtractor reading the token from the cookie with the given name,
+// e.g. for server-rendered pages storing the ID token in an HttpOnly cookie.
+// Missing and empty cookies are rejected with `ErrNoToken`.
+func FromCookie(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return "", fmt.Errorf("%w in cookie: %s", ErrNoToken, name)
+		}
+		return nonEmpty(c.Value, "cookie", name)
+	}
+}
+
+// FromQuery returns a TokenExtractor reading the token from the URL query parameter with the given name,
+// e.g. `?access_token=...`, for the clients that can't set headers, like the browser `EventSource` connections,
+// see `SSEHandler`, or the plain download links. Missing and empty parameters are rejected with `ErrNoToken`.
+//
+// Beware: the URLs, tokens included, end up in the access logs of the servers and the proxies along the way,
+// in the browser history and in the `Referer` header of the requests the page makes. Only opt in where headers
+// and cookies aren't an option, keeping the tokens short-lived and the logs out of reach.
+func FromQuery(param string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		return nonEmpty(r.URL.Query().Get(param), "query parameter", param)
+	}
+}
+
+// VerifyIDTokenFrom returns an `Engine.OnAuth` func like `VerifyIDToken`, but reading the token using the extractor
+// instead of the Authorization header:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnAuth = fauth.VerifyIDTokenFrom(fauth.FromCookie("token"))
+//	})
+func VerifyIDTokenFrom(extract TokenExtractor) func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	return func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+		jwt, err := extract(r)
+		if err != nil {
+			return nil, err
+		}
+		return verifyIDToken(r.Context(), client, jwt, false)
+	}
+}
+
+// FromForm returns a TokenExtractor reading the token from the given field of a form body.
+//
+// URL-encoded bodies are buffered, up to the `Engine.MaxBodyBytes`, and restored so the handler can read them again.
+// Multipart bodies, e.g. uploads posting the token alongside a file, are parsed using `r.ParseMultipartForm`,
+// keeping up to 256 KiB of the parts in memory and storing the rest in temporary files. They're limited to
+// the `Engine.MaxBodyBytes` as a whole, so the unauthenticated clients can't fill the disk; raise it to accept larger uploads.
+// The file parts aren't consumed by the extractor: the handler reads them from `r.MultipartForm`, e.g. using `r.FormFile`.
+// The temporary files are removed once the handler wrapped by `Auth` returns, or once the verification fails.
+func FromForm(field string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mt {
+		case "application/x-www-form-urlencoded":
+			b, err := bufferBody(r)
+			if err != nil {
+				return "", err
+			}
+			form, err := url.ParseQuery(string(b))
+			if err != nil {
+				return "", fmt.Errorf("fauth: invalid form: %w", err)
+			}
+			return nonEmpty(form.Get(field), "form field", field)
+		case "multipart/form-data":
+			limit := scopeFrom(r.Context()).engine.MaxBodyBytes
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(nil, r.Body, limit)
+			}
+			if err := r.ParseMultipartForm(min(limit, maxMultipartMemory)); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					return "", &statusError{code: http.StatusRequestEntityTooLarge, err: ErrBodyTooLarge}
+				}
+				return "", fmt.Errorf("fauth: invalid multipart form: %w", err)
+			}
+			var token string
+			if v := r.MultipartForm.Value[field]; len(v) > 0 {
+				token = v[0]
+			}
+			return nonEmpty(token, "form field", field)
+		}
+		return "", fmt.Errorf("fauth: invalid form content type: %s", mt)
+	}
+}
+
+// FromJSON returns a TokenExtractor reading the token from the given top-level string field of a JSON body.
+// The body is buffered, up to the `Engine.MaxBodyBytes`, and restored so the handler can read it again.
+func FromJSON(field string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		b, err := bufferBody(r)
+		if err != nil {
+			return "", err
+		}
+		var body map[string]any
+		if err := json.Unmarshal(b, &body); err != nil {
+			return "", fmt.Errorf("fauth: invalid JSON body: %w", err)
+		}
+		token, _ := body[field].(string)
+		return nonEmpty(token, "JSON field", field)
+	}
+}
+
+// FromTrailer returns a TokenExtractor reading the token from the given HTTP trailer, e.g. `Authorization`,
+// parsed like the Authorization header, using the `Engine.AuthScheme`. It's meant as a fallback for long-polling
+// clients refreshing their token mid-stream and sending it in the trailers of chunked requests.
+//
+// Trailers arrive after the body, so the extractor reads the whole body first, buffering it up to
+// the `Engine.MaxBodyBytes` and restoring it for the handler. The verification therefore can't start
+// before the client has finished sending the request.
+func FromTrailer(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		if _, err := bufferBody(r); err != nil {
+			return "", err
+		}
+		header := r.Trailer.Get(name)
+		if header == "" {
+			return "", fmt.Errorf("%w in trailer: %s", ErrNoToken, name)
+		}
+		return ParseAuthorization(header, scopeFrom(r.Context()).engine.AuthScheme)
+	}
+}
+
+// BearerFromHeaderRegexp returns a TokenExtractor applying the regexp to the value of the given header
+// and reading the token from the capture group with the given index, e.g. for gateways wrapping the token
+// in a templated header like `SSO token=abc; sig=...`:
+//
+//	fauth.BearerFromHeaderRegexp("X-SSO", regexp.MustCompile(`token=([^;]+)`), 1)
+//
+// Headers that don't match are rejected with `ErrMalformedHeader`. It panics if the group index
+// is out of the range of the capture groups of the regexp.
+func BearerFromHeaderRegexp(header string, re *regexp.Regexp, group int) TokenExtractor {
+	if group < 0 || group > re.NumSubexp() {
+		panic(fmt.Sprintf("fauth: group %d out of range, %s has %d capture groups", group, re, re.NumSubexp()))
+	}
+	return func(r *http.Request) (string, error) {
+		v := r.Header.Get(header)
+		if v == "" {
+			return "", fmt.Errorf("%w in header: %s", ErrNoToken, header)
+		}
+		m := re.FindStringSubmatch(v)
+		if m == nil {
+			return "", fmt.Errorf("%w: %s", ErrMalformedHeader, header)
+		}
+		return nonEmpty(m[group], "header", header)
+	}
+}
+
+// FirstOf returns a TokenExtractor trying the extractors in order and returning the first token read
+// without error, e.g. to accept a legacy header alongside the Authorization one during a migration:
+//
+//	e.TokenExtractor = fauth.FirstOf(fauth.Bearer, fauth.BearerFromHeaderRegexp("X-Auth-Token", regexp.MustCompile(`(.+)`), 1))
+//
+// If all of them fail, the errors are joined, in order, so `errors.Is` matches any of them,
+// e.g. `ErrNoToken` when no token was sent at all. Without extractors, it fails with `ErrNoToken`.
+func FirstOf(extractors ...TokenExtractor) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		if len(extractors) == 0 {
+			return "", ErrNoToken
+		}
+		errs := make([]error, 0, len(extractors))
+		for _, extract := range extractors {
+			token, err := extract(r)
+			if err == nil {
+				return token, nil
+			}
+			errs = append(errs, err)
+		}
+		return "", errors.Join(errs...)
+	}
+}
+
+func nonEmpty(token, source, name string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("%w in %s: %s", ErrNoToken, source, name)
+	}
+	return token, nil
+}
+
+// bufferBody reads the request body, up to the `Engine.MaxBodyBytes`, and restores it
+// so the handler can read it again. Larger bodies are rejected with 413 Request Entity Too Large.
+func bufferBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	limit := scopeFrom(r.Context()).engine.MaxBodyBytes
+	b, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(b), r.Body), Closer: r.Body}
+	if err != nil {
+		return nil, fmt.Errorf("fauth: failed to read the body: %w", err)
+	}
+	if int64(len(b)) > limit {
+		return nil, &statusError{code: http.StatusRequestEntityTooLarge, err: ErrBodyTooLarge}
+	}
+	return b, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,376 @@
+package fauth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+// extractorAuth returns a middleware func verifying the token read by the extractor.
+func extractorAuth(t *testing.T, extract fauth.TokenExtractor, opts ...fauth.Option) (func(http.HandlerFunc) http.HandlerFunc, func(claims map[string]any) string) {
+	t.Helper()
+
+	var v *fauthtest.Verifier
+	opts = append(opts, func(e *fauth.Engine) {
+		e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			jwt, err := extract(r)
+			if err != nil {
+				return nil, err
+			}
+			return v.VerifyIDToken(r.Context(), jwt)
+		}
+	})
+	withFirebaseAuth, sign := offlineAuth(t, opts...)
+	v = fauthtest.NewVerifier(&testKey.PublicKey)
+	return withFirebaseAuth, sign
+}
+
+func TestFromForm(t *testing.T) {
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FromForm("idToken"), func(e *fauth.Engine) {
+		e.MaxBodyBytes = 2048
+	})
+	var body string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	})
+
+	form := url.Values{"idToken": {sign(map[string]any{"sub": "uid"})}, "name": {"file.txt"}}.Encode()
+	serve := func(body, contentType string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://www.example.com", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(form, "application/x-www-form-urlencoded"); code != http.StatusOK {
+		t.Fatalf("invalid status: %d", code)
+	}
+	if body != form {
+		t.Fatalf("the body should be restored, got: %s", body)
+	}
+	if code := serve(form, "text/plain"); code != http.StatusUnauthorized {
+		t.Fatalf("invalid content type: invalid status: %d", code)
+	}
+	if code := serve("name=file.txt", "application/x-www-form-urlencoded"); code != http.StatusUnauthorized {
+		t.Fatalf("missing token: invalid status: %d", code)
+	}
+	if code := serve(form+"&pad="+strings.Repeat("a", 2048), "application/x-www-form-urlencoded"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large body: invalid status: %d", code)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FromJSON("idToken"))
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	for body, code := range map[string]int{
+		`{"idToken":"` + sign(map[string]any{"sub": "uid"}) + `"}`: http.StatusOK,
+		`{"idToken":42}`: http.StatusUnauthorized,
+		`{"idToken":`:    http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://www.example.com", strings.NewReader(body)))
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", body, code, w.Code)
+		}
+	}
+}
+
+func TestFromTrailer(t *testing.T) {
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FromTrailer("Authorization"))
+	var body string
+	srv := httptest.NewServer(withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	for trailer, code := range map[string]int{
+		"Bearer " + sign(map[string]any{"sub": "uid"}): http.StatusOK,
+		"": http.StatusUnauthorized,
+	} {
+		// Hide the length of the body to force a chunked request.
+		req, err := http.NewRequest(http.MethodPost, srv.URL, struct{ io.Reader }{strings.NewReader("poll")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Trailer = http.Header{"Authorization": {trailer}}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != code {
+			t.Fatalf("expected %d, got %d", code, res.StatusCode)
+		}
+	}
+	if body != "poll" {
+		t.Fatalf("the body should be restored, got: %s", body)
+	}
+}
+
+func TestBearerFromHeaderRegexp(t *testing.T) {
+	re := regexp.MustCompile(`^SSO token=([^;]*)`)
+	withFirebaseAuth, sign := extractorAuth(t, fauth.BearerFromHeaderRegexp("X-SSO", re, 1))
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	for header, code := range map[string]int{
+		"SSO token=" + sign(map[string]any{"sub": "uid"}) + "; sig=abc": http.StatusOK,
+		"SSO token=; sig=abc": http.StatusUnauthorized,
+		"Bearer abc":          http.StatusUnauthorized,
+		"":                    http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("X-SSO", header)
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", header, code, w.Code)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("out of range group should panic")
+		}
+	}()
+	fauth.BearerFromHeaderRegexp("X-SSO", re, 2)
+}
+
+func TestFromFormMultipart(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FromForm("idToken"), func(e *fauth.Engine) {
+		e.MaxBodyBytes = 512 << 10
+	})
+	var (
+		content string
+		fh      *multipart.FileHeader
+	)
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		content, fh = string(b), header
+	})
+
+	upload := strings.Repeat("a", 300<<10)
+	serve := func(token string) int {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		if token != "" {
+			_ = mw.WriteField("idToken", token)
+		}
+		fw, _ := mw.CreateFormFile("file", "file.txt")
+		_, _ = fw.Write([]byte(upload))
+		_ = mw.Close()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://www.example.com", &buf)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(sign(map[string]any{"sub": "uid"})); code != http.StatusOK {
+		t.Fatalf("invalid status: %d", code)
+	}
+	if content != upload {
+		t.Fatalf("the file should be readable by the handler, got %d bytes", len(content))
+	}
+	if f, err := fh.Open(); err == nil {
+		f.Close()
+		t.Fatal("the temporary file should be removed")
+	}
+	if code := serve(""); code != http.StatusUnauthorized {
+		t.Fatalf("missing token: invalid status: %d", code)
+	}
+	if code := serve("invalid"); code != http.StatusUnauthorized {
+		t.Fatalf("invalid token: invalid status: %d", code)
+	}
+	if files, _ := os.ReadDir(tmp); len(files) != 0 {
+		t.Fatalf("the temporary files of the failed requests should be removed: %v", files)
+	}
+
+	upload = strings.Repeat("a", 600<<10)
+	if code := serve(sign(map[string]any{"sub": "uid"})); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("too large: invalid status: %d", code)
+	}
+}
+
+func TestFromCookie(t *testing.T) {
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FromCookie("token"))
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	for value, code := range map[string]int{
+		sign(map[string]any{"sub": "uid"}): http.StatusOK,
+		"":                                 http.StatusUnauthorized,
+		"invalid":                          http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.AddCookie(&http.Cookie{Name: "token", Value: value})
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("%q: expected %d, got %d", value, code, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("X-Auth-Error") != "no_token" {
+		t.Fatalf("missing cookie: invalid status: %d", w.Code)
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FromQuery("access_token"))
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		query string
+		code  int
+		err   string
+	}{
+		{"?access_token=" + sign(map[string]any{"sub": "uid"}), http.StatusOK, ""},
+		{"?access_token=invalid", http.StatusUnauthorized, "invalid_token"},
+		{"?access_token=", http.StatusUnauthorized, "no_token"},
+		{"?token=" + sign(map[string]any{"sub": "uid"}), http.StatusUnauthorized, "no_token"},
+		{"", http.StatusUnauthorized, "no_token"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com/"+tt.query, nil))
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+}
+
+func TestVerifyIDTokenFrom(t *testing.T) {
+	fakeEmulator(t, map[string]string{"accounts:lookup": `{"users":[{"localId":"uid","validSince":"0"}]}`})
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = fauth.VerifyIDTokenFrom(fauth.FromCookie("token"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uid string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		uid = token.UID
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.AddCookie(&http.Cookie{Name: "token", Value: fauthtest.EmulatorToken("uid")})
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || uid != "uid" {
+		t.Fatalf("invalid status: %d, uid: %s", w.Code, uid)
+	}
+	if w := serveBearer(h, fauthtest.EmulatorToken("uid")); w.Code != http.StatusUnauthorized {
+		t.Fatalf("the Authorization header shouldn't be read, got: %d", w.Code)
+	}
+}
+
+func TestEngineTokenExtractor(t *testing.T) {
+	fromQuery := func(r *http.Request) (string, error) {
+		return r.URL.Query().Get("token"), nil
+	}
+	tests := []struct {
+		name    string
+		extract fauth.TokenExtractor
+		attach  func(r *http.Request, jwt string)
+	}{
+		{"header", nil, func(r *http.Request, jwt string) {
+			r.Header.Set("Authorization", "Bearer "+jwt)
+		}},
+		{"cookie", fauth.FromCookie("token"), func(r *http.Request, jwt string) {
+			r.AddCookie(&http.Cookie{Name: "token", Value: jwt})
+		}},
+		{"query", fromQuery, func(r *http.Request, jwt string) {
+			r.URL.RawQuery = url.Values{"token": {jwt}}.Encode()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+				e.TokenExtractor = tt.extract
+			})
+			h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+			for jwt, code := range map[string]int{
+				sign(map[string]any{"sub": "uid"}): http.StatusOK,
+				"invalid":                          http.StatusUnauthorized,
+			} {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest("", "http://www.example.com", nil)
+				tt.attach(r, jwt)
+				h.ServeHTTP(w, r)
+				if w.Code != code {
+					t.Fatalf("expected %d, got %d", code, w.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestFirstOf(t *testing.T) {
+	legacy := fauth.BearerFromHeaderRegexp("X-Auth-Token", regexp.MustCompile(`(.+)`), 1)
+	withFirebaseAuth, sign := extractorAuth(t, fauth.FirstOf(fauth.Bearer, legacy))
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	jwt := sign(map[string]any{"sub": "uid"})
+
+	tests := []struct {
+		header map[string]string
+		code   int
+		err    string
+	}{
+		{map[string]string{"Authorization": "Bearer " + jwt}, http.StatusOK, ""},
+		{map[string]string{"X-Auth-Token": jwt}, http.StatusOK, ""},
+		{map[string]string{"Authorization": "Basic abc", "X-Auth-Token": jwt}, http.StatusOK, ""},
+		{map[string]string{"Authorization": "Bearer invalid", "X-Auth-Token": jwt}, http.StatusUnauthorized, "invalid_token"},
+		{nil, http.StatusUnauthorized, "no_token"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+
+	_, err := fauth.FirstOf()(httptest.NewRequest("", "http://www.example.com", nil))
+	if !errors.Is(err, fauth.ErrNoToken) {
+		t.Fatalf("expected ErrNoToken, got %v", err)
+	}
+}
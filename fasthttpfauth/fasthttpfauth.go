@@ -0,0 +1,46 @@
+// Package fasthttpfauth verifies the Firebase ID tokens carried by fasthttp requests,
+// for the services mixing net/http and fasthttp.
+//
+// It's a separate module, so the fauth package doesn't depend on fasthttp.
+package fasthttpfauth
+
+import (
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/valyala/fasthttp"
+)
+
+// TokenKey is the user value key the verified `*auth.Token` is stored under in the `fasthttp.RequestCtx`.
+const TokenKey = "fauth.token"
+
+// VerifyFastHTTP verifies the bearer token of the Authorization header of the request, storing the verified token
+// in the `fasthttp.RequestCtx` under the `TokenKey`, see `Token`. It's the fasthttp analog of `fauth.VerifyRequest`:
+//
+//	func handler(ctx *fasthttp.RequestCtx) {
+//		token, err := fasthttpfauth.VerifyFastHTTP(ctx, client)
+//		if err != nil {
+//			ctx.Error(fauth.ErrorCode(err), fauth.StatusCode(err))
+//			return
+//		}
+//		...
+//	}
+//
+// Like `fauth.VerifyRequest`, it doesn't check whether the token has been revoked.
+func VerifyFastHTTP(ctx *fasthttp.RequestCtx, client *auth.Client) (*auth.Token, error) {
+	jwt, err := fauth.ParseBearer(string(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization)))
+	if err != nil {
+		return nil, err
+	}
+	token, err := fauth.VerifyString(ctx, client, jwt)
+	if err != nil {
+		return nil, err
+	}
+	ctx.SetUserValue(TokenKey, token)
+	return token, nil
+}
+
+// Token returns the token verified by `VerifyFastHTTP`, if any.
+func Token(ctx *fasthttp.RequestCtx) (*auth.Token, bool) {
+	token, ok := ctx.UserValue(TokenKey).(*auth.Token)
+	return token, ok && token != nil
+}
@@ -0,0 +1,52 @@
+package fasthttpfauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fasthttpfauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestVerifyFastHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"users":[{"localId":"uid","validSince":"0"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	ctx := context.Background()
+	app, err := fauthtest.NewApp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside of a server, the RequestCtx must be initialized to be used as a context.Context.
+	var rctx fasthttp.RequestCtx
+	rctx.Init(&fasthttp.Request{}, nil, nil)
+	if _, err := fasthttpfauth.VerifyFastHTTP(&rctx, client); !errors.Is(err, fauth.ErrNoToken) {
+		t.Fatalf("expected ErrNoToken, got %v", err)
+	}
+	if _, ok := fasthttpfauth.Token(&rctx); ok {
+		t.Fatal("no token should be stored on failure")
+	}
+
+	rctx.Request.Header.Set("Authorization", "Bearer "+fauthtest.EmulatorToken("uid"))
+	token, err := fasthttpfauth.VerifyFastHTTP(&rctx, client)
+	if err != nil || token.UID != "uid" {
+		t.Fatalf("unexpected result: %v, %v", token, err)
+	}
+	if stored, ok := fasthttpfauth.Token(&rctx); !ok || stored != token {
+		t.Fatal("the token should be stored in the request context")
+	}
+}
@@ -2,10 +2,18 @@ package fauth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
 )
@@ -17,27 +25,110 @@ func Bearer(r *http.Request) (string, error) {
 
 // ParseBearer parses the Authorization header string and returns the bearer value.
 // It expects it to be of form `Bearer eyJhbGciOi`...
+// Trailing RFC 7235 auth-params, e.g. `Bearer eyJhbGciOi, realm="api"`, are tolerated and ignored.
 func ParseBearer(header string) (string, error) {
-	t := strings.Split(header, " ")
-	if len(t) != 2 || len(t[1]) == 0 || strings.ToLower(t[0]) != "bearer" {
-		return "", fmt.Errorf("fauth: invalid header: %s", header)
+	return ParseAuthorization(header, "bearer")
+}
+
+// ParseAuthorization is like `ParseBearer`, but accepts the given authentication scheme instead,
+// e.g. `JWT` for headers of form `JWT eyJhbGciOi`... The scheme is case-insensitive.
+func ParseAuthorization(header, scheme string) (string, error) {
+	if header == "" {
+		return "", ErrNoToken
+	}
+	s, credentials, _ := strings.Cut(header, " ")
+	token, params, _ := strings.Cut(credentials, ",")
+	// The errors never carry the header, which may be a valid token sent with the wrong scheme, as they may be logged.
+	if !strings.EqualFold(s, scheme) || len(token) == 0 || strings.ContainsAny(token, " \t") {
+		return "", fmt.Errorf("%w: expected the %s scheme", ErrMalformedHeader, scheme)
 	}
-	return t[1], nil
+	if err := parseAuthParams(params); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformedHeader, err)
+	}
+	return token, nil
 }
 
-// VerifyIDToken verifies the request is coming from a valid Firebase user.
-// It does not check whether the token has been revoked or disabled, use `VerifyIDTokenAndCheckRevoked`
+// ExtractToken returns the token of the request the way the Engine serving it is configured to,
+// i.e. using the `Engine.TokenExtractor` or, by default, from the Authorization header using the `Engine.AuthScheme`,
+// rejecting the non-JWTs if `Engine.RejectNonJWT` is set.
+// Outside of `Auth`, it's equivalent to `Bearer`.
+func ExtractToken(r *http.Request) (string, error) {
+	e := scopeFrom(r.Context()).engine
+	var (
+		token string
+		err   error
+	)
+	if e.TokenExtractor != nil {
+		token, err = e.TokenExtractor(r)
+	} else {
+		token, err = ParseAuthorization(r.Header.Get("Authorization"), e.AuthScheme)
+	}
+	if err != nil {
+		return "", err
+	}
+	if e.RejectNonJWT && !LooksLikeJWT(token) {
+		return "", fmt.Errorf("%w: not a JWT", ErrMalformedHeader)
+	}
+	return token, nil
+}
+
+// VerifyRequest verifies the request is coming from a valid Firebase user, returning its token.
+// It's meant for imperative use inside handlers that aren't wrapped by `Auth`:
+//
+//	token, err := fauth.VerifyRequest(r, client)
+//	if err != nil {
+//		w.WriteHeader(http.StatusUnauthorized)
+//		return
+//	}
+//
+// It does not check whether the token has been revoked or disabled, use `VerifyRequestAndCheckRevoked`
 // if a revocation check is needed.
-func VerifyIDToken(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
-	jwt, err := Bearer(r)
+func VerifyRequest(r *http.Request, client *auth.Client) (*auth.Token, error) {
+	jwt, err := ExtractToken(r)
 	if err != nil {
 		return nil, err
 	}
-	token, err := client.VerifyIDToken(r.Context(), jwt)
+	return VerifyString(r.Context(), client, jwt)
+}
+
+// VerifyString verifies the ID token, with no HTTP involvement, e.g. for CLI tools checking a pasted token
+// before calling an API on behalf of the user. Like `VerifyIDToken`, it doesn't check whether the token has been revoked.
+func VerifyString(ctx context.Context, client *auth.Client, jwt string) (*auth.Token, error) {
+	if jwt == "" {
+		return nil, ErrNoToken
+	}
+	return verifyIDToken(ctx, client, jwt, false)
+}
+
+// VerifyStdin is like `VerifyString`, but it reads the token from the standard input, ignoring the surrounding whitespace:
+//
+//	$ echo "$ID_TOKEN" | mycli whoami
+func VerifyStdin(ctx context.Context, client *auth.Client) (*auth.Token, error) {
+	b, err := io.ReadAll(io.LimitReader(os.Stdin, maxStdinBytes))
 	if err != nil {
-		return nil, fmt.Errorf("fauth: failed to verify the token: %w", err)
+		return nil, fmt.Errorf("fauth: failed to read the token: %w", err)
 	}
-	return token, nil
+	return VerifyString(ctx, client, strings.TrimSpace(string(b)))
+}
+
+// maxStdinBytes bounds the input read by `VerifyStdin`, well above the size of the ID tokens.
+const maxStdinBytes = 64 << 10
+
+// VerifyRequestAndCheckRevoked is like `VerifyRequest`, but it also checks the token hasn't been revoked.
+// Like `VerifyIDTokenAndCheckRevoked`, it makes an RPC call to perform the revocation check.
+func VerifyRequestAndCheckRevoked(r *http.Request, client *auth.Client) (*auth.Token, error) {
+	jwt, err := ExtractToken(r)
+	if err != nil {
+		return nil, err
+	}
+	return verifyIDToken(r.Context(), client, jwt, true)
+}
+
+// VerifyIDToken verifies the request is coming from a valid Firebase user.
+// It does not check whether the token has been revoked or disabled, use `VerifyIDTokenAndCheckRevoked`
+// if a revocation check is needed.
+func VerifyIDToken(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	return VerifyRequest(r, client)
 }
 
 // VerifyIDTokenAndCheckRevoked verifies the request is coming from a valid Firebase user
@@ -53,15 +144,7 @@ func VerifyIDToken(r *http.Request, app *firebase.App, client *auth.Client) (any
 //		e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked
 //	})
 func VerifyIDTokenAndCheckRevoked(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
-	jwt, err := Bearer(r)
-	if err != nil {
-		return nil, err
-	}
-	token, err := client.VerifyIDTokenAndCheckRevoked(r.Context(), jwt)
-	if err != nil {
-		return nil, fmt.Errorf("fauth: failed to verify the token: %w", err)
-	}
-	return token, nil
+	return VerifyRequestAndCheckRevoked(r, client)
 }
 
 type contextKey string
@@ -80,9 +163,19 @@ func WithAuthData(ctx context.Context, data any) context.Context {
 }
 
 // AuthToken returns the Firebase Token.
-// This assumes the stock Firebase Token is returned by the `Engine.OnAuth` func.
+// This assumes the stock Firebase Token, or a `*Result` embedding it, is returned by the `Engine.OnAuth` func.
 func AuthToken(ctx context.Context) (*auth.Token, bool) {
-	token, ok := AuthData(ctx).(*auth.Token)
+	return tokenOf(AuthData(ctx))
+}
+
+func tokenOf(data any) (*auth.Token, bool) {
+	if res, ok := data.(*Result); ok {
+		if res == nil {
+			return nil, false
+		}
+		return res.Token, true
+	}
+	token, ok := data.(*auth.Token)
 	return token, ok
 }
 
@@ -96,11 +189,45 @@ func defaultNewApp(ctx context.Context) (*firebase.App, error) {
 
 func defaultOnData(r *http.Request, data any) (*http.Request, error) {
 	ctx := WithAuthData(r.Context(), data)
+	if token, ok := AuthToken(ctx); ok && token != nil {
+		ctx = WithClaims(ctx, token.Claims)
+	}
 	return r.WithContext(ctx), nil
 }
 
 func defaultOnErr(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
-	w.WriteHeader(http.StatusUnauthorized)
+	writeError(w, r, err, scopeFrom(r.Context()).engine.JSONErrors)
+}
+
+// writeError answers the request with the status code of the error, along with the `X-Auth-Error` header
+// and, for 401 Unauthorized, the `WWW-Authenticate` challenge.
+func writeError(w http.ResponseWriter, r *http.Request, err error, json bool) {
+	engine := scopeFrom(r.Context()).engine
+	code := engine.status(err)
+	if c := ErrorCode(err); c != "" {
+		w.Header().Set("X-Auth-Error", c)
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", engine.challenge(err))
+	}
+	if json {
+		WriteJSONError(w, code, err)
+		return
+	}
+	if engine.Debug {
+		http.Error(w, errorReason(err, code), code)
+		return
+	}
+	w.WriteHeader(code)
+}
+
+// status returns the status code the Engine answers the error with, see `StatusCode` and `Engine.UnauthorizedStatus`.
+func (e *Engine) status(err error) int {
+	code := StatusCode(err)
+	if code == http.StatusUnauthorized && e.UnauthorizedStatus != 0 {
+		code = e.UnauthorizedStatus
+	}
+	return code
 }
 
 // Option allows you to override the Engine defaults, e.g.:
@@ -114,11 +241,237 @@ func defaultOnErr(w http.ResponseWriter, r *http.Request, app *firebase.App, cli
 //	})
 type Option func(*Engine)
 
+// WithoutClose marks the Engine as compiled by a constructor discarding its Authenticator, e.g. `Auth`,
+// so nothing can call `Authenticator.Close`. `Engine.Validate` then rejects the fields starting the background work
+// only Close stops, i.e. `KeyRefreshInterval`, `OnActive` and `InjectFirestore`, rather than leaking it.
+// It's applied by the constructors of this package and of the adapters, e.g. `echofauth.Middleware`;
+// to use those fields, create the Authenticator with `NewAuthenticator` and pass it to their `For` variants.
+func WithoutClose(constructor string) Option {
+	return func(e *Engine) {
+		e.withoutClose = constructor
+	}
+}
+
+var (
+	defaultsMu sync.RWMutex
+	defaults   []Option
+)
+
+// SetDefaults registers package-level options, applied by `Auth` and `NewAuthenticator` before the ones passed
+// to them, so the per-call options take precedence. It replaces the previously registered defaults.
+// It's meant for apps using the same configuration in many places:
+//
+//	func init() {
+//		fauth.SetDefaults(func(e *fauth.Engine) {
+//			e.Realm = "api"
+//		})
+//	}
+//
+// SetDefaults is safe for concurrent use, but the defaults only apply to the Engines created afterwards,
+// so set them once at init, before creating any middleware. They don't apply to the funcs used outside of it,
+// e.g. `VerifyString` or `ExtractToken`.
+func SetDefaults(opts ...Option) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaults = append([]Option(nil), opts...)
+}
+
+// OnAuthFunc verifies the request, returning the auth data, e.g. the `*auth.Token`. See `Engine.OnAuth`.
+type OnAuthFunc func(r *http.Request, app *firebase.App, client *auth.Client) (any, error)
+
 type Engine struct {
 	NewApp func(ctx context.Context) (*firebase.App, error)
-	OnAuth func(r *http.Request, app *firebase.App, client *auth.Client) (any, error)
+	OnAuth OnAuthFunc
 	OnData func(r *http.Request, data any) (*http.Request, error)
 	OnErr  func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error)
+	// TokenExtractor, if set, reads the token from the request instead of the Authorization header, e.g. `FromCookie`.
+	// It's consulted by `ExtractToken`, so by the built-in `OnAuth` funcs like `VerifyIDToken`, letting you swap
+	// the token source without reimplementing the verification.
+	TokenExtractor TokenExtractor
+	// AuthScheme is the authentication scheme of the Authorization header, defaulting to `bearer`.
+	// Set it to accept non-standard headers, e.g. `JWT eyJhbGciOi`...
+	AuthScheme string
+	// RejectNonJWT rejects the tokens that aren't structurally JWTs, see `LooksLikeJWT`, with `ErrMalformedHeader`
+	// before they reach the verifier, avoiding wasting a verify call on garbage.
+	RejectNonJWT bool
+	// Realm, if set, is included in the RFC 6750 `WWW-Authenticate` challenge of the 401 responses, e.g. `Bearer realm="api"`,
+	// letting clients of multi-API hosts tell which API they failed to authenticate against.
+	Realm string
+	// Optional lets the requests without a valid token through: when the token is missing or fails the verification,
+	// e.g. because it's malformed or expired, the handler is called with no auth data, `AuthData` returning nil,
+	// for endpoints serving both the signed-in and the anonymous users. Other failures, e.g. verifications
+	// timing out or tokens of another project, are still passed to `OnErr`, and so are the failures of the
+	// `Require*` middleware funcs wrapped by it.
+	Optional bool
+	// TrustedProxies are the networks of the reverse proxies, e.g. the load balancers, whose `X-Forwarded-For` header
+	// is trusted to carry the address of the client, see `ClientIP`. By default, no proxy is trusted.
+	TrustedProxies []net.IPNet
+	// PrivateCacheControl sets the `Cache-Control` header of the successfully authenticated responses to
+	// `CacheControl` before the handler runs, keeping the shared caches, e.g. CDNs, from storing personalized
+	// responses. Handlers can still override it. It only applies to `Wrap`, not to `Authenticate`.
+	PrivateCacheControl bool
+	// CacheControl is the `Cache-Control` header value set by `PrivateCacheControl`, defaulting to "private, no-store".
+	CacheControl string
+	// HealthPath, if set, is answered with a 200 status without running the auth or the handler,
+	// exposing a health endpoint, e.g. `/healthz`, through the wrapped handler.
+	HealthPath string
+	// Skip, if set, lets the requests it returns true for through to the handler without any verification, e.g. the
+	// metrics endpoints of a globally mounted middleware, see `SkipPaths`. The handler runs with no auth data,
+	// `AuthData` returning nil. It only applies to `Wrap`, not to `Authenticate`.
+	Skip func(r *http.Request) bool
+	// SkipPreflight lets the CORS preflight requests, i.e. the `OPTIONS` requests carrying
+	// an `Access-Control-Request-Method` header, through to the handler without any verification, like `Skip`.
+	// Browsers never send credentials with them, so they'd fail the verification otherwise.
+	SkipPreflight bool
+	// PreVerify, if set, runs before the token is extracted and verified, acting as a cheap gate ahead of
+	// the expensive crypto, e.g. to reject blocked IPs. A non-nil error is passed to `OnErr`, with a 403 status
+	// unless it's a `StatusError`.
+	PreVerify func(r *http.Request) error
+	// VerifyBinding, if set, runs once the token is verified, checking it's bound to the request,
+	// e.g. to the TLS client certificate using `CertificateBinding`. A non-nil error is passed to `OnErr`.
+	VerifyBinding func(r *http.Request, token *auth.Token) error
+	// MaxBodyBytes limits the size of the bodies read by the body extractors, e.g. `FromForm`, multipart uploads included.
+	// It defaults to 1 MiB; larger bodies are rejected with 413 Request Entity Too Large.
+	MaxBodyBytes int64
+	// LoadProfile, if set, loads the profile of the verified user, e.g. from Firestore, once the token is verified.
+	// The profile is available to the handlers through the `Profile` func.
+	LoadProfile func(ctx context.Context, uid string) (any, error)
+	// Permissions, if set, resolves the permissions of the verified user, e.g. computing them from their roles,
+	// once the token is verified. The permissions are available to the handlers through the `Permissions` func
+	// and checked by `RequirePermission`. Errors are answered with 503 Service Unavailable.
+	Permissions func(ctx context.Context, token *auth.Token) ([]string, error)
+	// WasAnonymous, if set, reports whether the user with the UID was ever anonymous, letting the `Upgraded` func
+	// tell the upgraded anonymous accounts apart. Its errors leave the upgrade status unknown.
+	WasAnonymous func(ctx context.Context, uid string) (bool, error)
+	// OnDataFatal makes the failures of the data loading hooks, e.g. `LoadProfile`, fatal:
+	// the request is passed to `OnErr` instead of reaching the handler without the data.
+	OnDataFatal bool
+	// Logger, if set, is annotated with the `uid` and `provider` of the verified token, along with the `request_id`
+	// if any, and stored in the request context. Handlers retrieve it using the `Logger` func.
+	// The verification failures are logged to it too, along with the request path and a prefix of the token,
+	// never the full token, helping to debug spikes of 401s. Nil means no logging.
+	Logger *slog.Logger
+	// RequestIDHeader, if set, is the header the ID of the request is read from, e.g. `X-Request-ID`, tying
+	// the traces to the identity of the user. Requests without the header, or with an ID longer than 128 bytes
+	// or with characters outside of `[A-Za-z0-9._-]`, are assigned a random ID.
+	// The ID is echoed in the response headers and available to the handlers through the `RequestID` func.
+	RequestIDHeader string
+	// SessionCookieName is the name of the session cookie set by `LoginHandler`, defaulting to `session`.
+	SessionCookieName string
+	// SessionLifetime is the lifetime of the session cookies minted by `LoginHandler`, defaulting to 5 days.
+	// Firebase accepts lifetimes between 5 minutes and 2 weeks.
+	SessionLifetime time.Duration
+	// ClaimNamespace, if set, is stripped from the claim keys of the verified token, e.g. `https://app.com/` turns
+	// the `https://app.com/roles` claim into `roles`, easing the migration from IdPs namespacing their claims, like Auth0.
+	// The normalization runs right after `OnAuth`, before any authorization check, e.g. the `Require*` funcs.
+	// Claims already present under the stripped key take precedence over the namespaced ones.
+	ClaimNamespace string
+	// RolesClaim is the claim the `Roles` func reads the roles of the user from, defaulting to `roles`.
+	// Nested claims can be referenced using the dot notation, e.g. `app.roles`.
+	RolesClaim string
+	// OnActive, if set, is called with the UID of every successfully verified request, e.g. to record the last-seen
+	// time of the users. It runs asynchronously, on a single background worker fed by a bounded queue, so it never
+	// blocks the request handling: when the worker can't keep up, the calls are dropped and reported to `OnMetric`
+	// as `active_dropped`. Use `NewAuthenticator` and call `Authenticator.Close` to stop the worker; the constructors
+	// discarding the Authenticator, e.g. `Auth`, reject it, see `WithoutClose`.
+	OnActive func(ctx context.Context, uid string)
+	// ProjectID, if set, is the ID of the Firebase project the tokens must be issued for. Once the token is verified,
+	// its `aud` and `iss` claims are checked against it, rejecting the tokens of other projects with 403 Forbidden
+	// and `ErrWrongProject`, even when the verifier accepted them. It's required by `KeyRefreshInterval`.
+	ProjectID string
+	// MaxTokenLifetime, if set, rejects the verified tokens claiming a longer lifetime, i.e. their `exp` minus
+	// their `iat`, with `ErrInvalidToken`, as a sanity check against malformed or manipulated tokens.
+	// Firebase ID tokens last an hour; session cookies, see `VerifySessionCookie`, up to two weeks.
+	MaxTokenLifetime time.Duration
+	// TenantID, if set, resolves the Firebase Auth tenant of the request, e.g. using `TenantFromSubdomain`
+	// or reading a header. The built-in ID token verifiers, e.g. `VerifyIDToken`, verify the tokens against the
+	// auth client of the tenant, and the tokens issued by other tenants, or by the project itself, are rejected
+	// with `ErrInvalidToken`, whichever verifier is used. Failures to resolve the tenant are passed to `OnErr`.
+	// The tenant is available to the handlers through the `Tenant` func, and `VerifyIDTokenWithUser` fetches
+	// the user records from the tenant. `LoginHandler` rejects it, as the session cookies are only minted for the project users.
+	TenantID func(r *http.Request) (string, error)
+	// AudienceFunc, if set, returns the audiences acceptable for the request, e.g. depending on the product
+	// in its path or host. Once the token is verified, its audience, see `RequireAudience`, must include one of them,
+	// otherwise the request is rejected with 403 Forbidden and `ErrWrongAudience`. No audience rejects all the tokens.
+	AudienceFunc func(r *http.Request) []string
+	// KeyRefreshInterval, if set, makes the Authenticator refresh the public keys used to verify the tokens in the background,
+	// avoiding the latency spike of the first request after the key cache of the Firebase Admin SDK expires.
+	// The tradeoff is an extra background request to Google every time the keys expire, whether or not requests come in.
+	// It requires the `ProjectID`; use `NewAuthenticator` and call `Authenticator.Close` to stop the refresh,
+	// the constructors discarding the Authenticator, e.g. `Auth`, reject it, see `WithoutClose`.
+	KeyRefreshInterval time.Duration
+	// UserCacheTTL, if set, caches the user records fetched by `VerifyIDTokenWithUser` for the given duration,
+	// so changes to the users, e.g. disabling them, take up to the TTL to be picked up.
+	UserCacheTTL time.Duration
+	// UserCacheSize bounds the number of the cached user records, evicting the least recently used ones.
+	// It defaults to 1024.
+	UserCacheSize int
+	// TokenCacheTTL, if set, caches the tokens verified by the built-in ID token verifiers, e.g. `VerifyIDToken`,
+	// keyed by the raw JWT, for the given duration or until they expire, whichever comes first. The repeated requests
+	// carrying the same token skip the signature verification and, with `VerifyIDTokenAndCheckRevoked`, the revocation
+	// check RPC, so revoking a token or disabling its user takes up to the TTL to be picked up.
+	TokenCacheTTL time.Duration
+	// Verifier, if set, verifies the ID tokens for the built-in ID token verifiers, e.g. `VerifyIDToken`, instead of
+	// the Firebase Auth client, or the one of the tenant, see `TenantID`. It's meant for tests, e.g. using the fake
+	// of the `fauthtest` package to run the middleware fully offline.
+	Verifier Verifier
+	// InjectFirestore makes the Authenticator create a Firestore client along with the Firebase app, shared by
+	// the requests, which the handlers retrieve using `FirestoreFromContext`. It requires the project ID of the app.
+	// The client is closed by `Authenticator.Close`, so the constructors discarding the Authenticator, e.g. `Auth`,
+	// reject it, see `WithoutClose`; the ones replaced by `Authenticator.Reload` are left to the in-flight requests
+	// for a minute before being closed.
+	InjectFirestore bool
+	// ServeStaleTokens degrades the verification gracefully during the outages of Firebase: when the verification
+	// of a token fails transiently, e.g. the public keys can't be fetched or the revocation check RPC times out,
+	// the token is still accepted if it was verified and cached before, see `TokenCacheTTL`, past the TTL but
+	// never past its expiry, keeping the recently active users working. The tokens that weren't cached are rejected
+	// with 503 Service Unavailable and `ErrVerifierUnavailable`.
+	//
+	// Mind the security posture: during an outage, the tokens revoked, and the users disabled, after the last
+	// successful verification of the token are accepted until the token expires, i.e. for up to an hour for the ID tokens.
+	// The tokens that were never verified are never accepted. It has no effect without `TokenCacheTTL`.
+	ServeStaleTokens bool
+	// TokenCacheSize bounds the number of the cached tokens, evicting the least recently used ones.
+	// It defaults to 1024.
+	TokenCacheSize int
+	// OnMetric, if set, is called with the metrics of the middleware, e.g. `user_cache_hit` and `user_cache_miss`.
+	OnMetric func(name string, value float64)
+	// OnVerify, if set, is called after each verification, i.e. each run of the `OnAuth` func including the revocation
+	// check RPC, with its outcome and its duration, e.g. to feed Prometheus counters and latency histograms without
+	// fauth depending on their client library. The outcome is `ok` on success, the code of the error otherwise,
+	// see `ErrorCode`, e.g. `malformed`, `expired` or `revoked`, `network` for the transient failures without a code,
+	// e.g. the public keys failing to be fetched, and `error` for the other ones. The durations are measured with `Now`.
+	OnVerify func(outcome string, d time.Duration)
+	// UnauthorizedStatus, if set, replaces the 401 Unauthorized status the default `OnErr` answers the authentication
+	// failures with, e.g. 403 Forbidden or 404 Not Found to hide the endpoint.
+	UnauthorizedStatus int
+	// JSONErrors makes the default `OnErr` respond with a JSON payload clients can parse, see `WriteJSONError`,
+	// instead of an empty body.
+	JSONErrors bool
+	// Debug makes the default `OnErr` respond with a short plain-text reason, e.g. `token expired`, instead of an empty body.
+	// It's meant for development: keep it off in production to avoid leaking the details of the failures.
+	Debug bool
+	// DefaultTimeout, if set, bounds the verification, i.e. the `OnAuth` func, of the requests whose context
+	// has no deadline, protecting against hung verifications. Deadlines set by the callers are respected as they are.
+	// Verifications timing out are answered with 503 Service Unavailable and `ErrTimeout`.
+	DefaultTimeout time.Duration
+	// VerifyTimeout, if set, bounds the verification, i.e. the `OnAuth` func and the Firebase RPCs it makes, of all
+	// the requests, whether their context has a deadline or not, the earliest deadline winning. Unlike `DefaultTimeout`,
+	// it also shortens the deadlines set by the callers; when both are set, the requests without a deadline are bounded
+	// by the shorter of the two. Verifications timing out are answered with 503 Service Unavailable and `ErrTimeout`,
+	// which a custom `OnErr` can answer with 401 Unauthorized instead. Zero means no timeout.
+	VerifyTimeout time.Duration
+	// TraceVerify, if set, is called before each verification, i.e. each run of the `OnAuth` func, returning the context
+	// the verification runs in, e.g. carrying a child span of the one of the request, and the func called once it's done,
+	// with the trace of the verification, e.g. to end the span. See the `otelfauth` package for OpenTelemetry tracing.
+	TraceVerify func(ctx context.Context) (context.Context, func(t *VerifyTrace))
+	// Now is the clock used by the time-dependent checks, e.g. the `Require*` middleware funcs.
+	// It defaults to `time.Now`, override it to freeze time in tests.
+	Now func() time.Time
+
+	stats        *stats
+	withoutClose string
+	emulatorHost string
 }
 
 // Auth returns a middleware func verifying the request is coming from a valid Firebase user.
@@ -133,10 +486,296 @@ type Engine struct {
 //		w.Write([]byte("Hey, ma!"))
 //	}))
 func Auth(ctx context.Context, opts ...Option) (func(http.HandlerFunc) http.HandlerFunc, error) {
+	a, err := NewAuthenticator(ctx, append([]Option{WithoutClose("Auth")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return a.Wrap, nil
+}
+
+// AuthHandler is like `Auth`, but the middleware wraps an `http.Handler`, following the stdlib idiom
+// expected by the routers and the middleware chains, e.g. chi or gorilla/mux:
+//
+//	withFirebaseAuth, err := fauth.AuthHandler(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	r := chi.NewRouter()
+//	r.Use(withFirebaseAuth)
+func AuthHandler(ctx context.Context, opts ...Option) (func(http.Handler) http.Handler, error) {
+	a, err := NewAuthenticator(ctx, append([]Option{WithoutClose("AuthHandler")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return a.Handler, nil
+}
+
+// Authenticator is the compiled form of an Engine, holding its Firebase app and Auth client.
+// Use it instead of `Auth` when you need to manage it after creation, e.g. to reload the credentials.
+type Authenticator struct {
+	engine *Engine
+	mu     sync.RWMutex
+	scope  *scope
+
+	users     *lruCache[*auth.UserRecord]
+	tokens    *lruCache[cachedToken]
+	active    chan activity
+	stop      context.CancelFunc
+	done      <-chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewAuthenticator creates an Authenticator, initializing the Firebase app using the `Engine.NewApp` func.
+func NewAuthenticator(ctx context.Context, opts ...Option) (*Authenticator, error) {
+	a := &Authenticator{engine: newEngine(opts...)}
+	if err := a.engine.Validate(); err != nil {
+		return nil, err
+	}
+	if a.engine.UserCacheTTL > 0 {
+		a.users = newLRUCache[*auth.UserRecord](a.engine.UserCacheSize, a.engine.Now)
+	}
+	if a.engine.TokenCacheTTL > 0 {
+		a.tokens = newLRUCache[cachedToken](a.engine.TokenCacheSize, a.engine.Now)
+	}
+	if err := a.Reload(ctx); err != nil {
+		return nil, err
+	}
+	a.start(ctx)
+	return a, nil
+}
+
+// Reload re-initializes the Firebase app and Auth client using the `Engine.NewApp` func, e.g. to pick up
+// a rotated service account key without restarting the process.
+//
+// The new client is swapped in atomically: in-flight requests finish using the client they started with,
+// while new requests use the new one. If the initialization fails, the current client is kept.
+func (a *Authenticator) Reload(ctx context.Context) error {
+	app, err := a.engine.NewApp(ctx)
+	if err != nil {
+		return fmt.Errorf("fauth: error initializing firebase: %w", err)
+	}
+	cli, err := authClient(ctx, app, a.engine.emulatorHost)
+	if err != nil {
+		return fmt.Errorf("fauth: error initializing firebase auth: %w", err)
+	}
+	var fs *firestore.Client
+	if a.engine.InjectFirestore {
+		if fs, err = app.Firestore(ctx); err != nil {
+			return fmt.Errorf("fauth: error initializing firestore: %w", err)
+		}
+	}
+	a.mu.Lock()
+	old := a.scope
+	a.scope = &scope{engine: a.engine, app: app, client: cli, users: a.users, tokens: a.tokens, firestore: fs}
+	a.mu.Unlock()
+	if old != nil && old.firestore != nil {
+		a.retire(old.firestore)
+	}
+	return nil
+}
+
+func (a *Authenticator) current() *scope {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.scope
+}
+
+// Wrap is the middleware func verifying the request is coming from a valid Firebase user.
+func (a *Authenticator) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := a.current()
+		if s.engine.HealthPath != "" && r.URL.Path == s.engine.HealthPath {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if s.engine.skip(r) {
+			// The skipped handlers still get the scope, e.g. for `ClientIP` and `Now`.
+			serve(h, w, r.WithContext(context.WithValue(r.Context(), scopeContextKey, s)))
+			return
+		}
+		req, _, err := a.verify(s, w, r)
+		s.engine.stats.record(err)
+		s.engine.logFailure(req, err)
+		if err != nil {
+			removeForm(req)
+			s.engine.OnErr(w, req, s.app, s.client, err)
+			return
+		}
+		serve(h, w, req)
+	}
+}
+
+// Authenticate runs the verification of `Wrap` on the request, i.e. the `Engine` hooks from `PreVerify` to `OnData`,
+// returning the request carrying the auth data, or the error `Wrap` would pass to `OnErr`, see `StatusError`.
+// It's meant for the transports that aren't served by an `http.Handler`, e.g. gRPC interceptors translating
+// their metadata to the request headers. With `Engine.Optional` set, the requests without a valid token
+// are returned with no auth data. On success, the caller owns the multipart form parsed by `FromForm`, if any,
+// and removes its temporary files once done, see `http.Request.MultipartForm`; on failure, they're already removed.
+func (a *Authenticator) Authenticate(r *http.Request) (*http.Request, error) {
+	s := a.current()
+	req, _, err := a.verify(s, nil, r)
+	s.engine.stats.record(err)
+	s.engine.logFailure(req, err)
+	if err != nil {
+		removeForm(req)
+	}
+	return req, err
+}
+
+// AuthenticateHTTP is like `Authenticate`, but for the frameworks whose handlers get the `http.ResponseWriter`,
+// e.g. Echo or Gin, so it honors the `Engine` fields `Wrap` writes the response for. It answers the `Engine.HealthPath`
+// itself, returning a nil request, lets the skipped requests, see `Engine.Skip`, through with no auth data,
+// and sets the response headers: the request ID, the `Engine.CacheControl` of `Engine.PrivateCacheControl`
+// and, for the 401 Unauthorized errors, the `WWW-Authenticate` challenge. The error response itself is left
+// to the caller, e.g. to the error handler of the framework.
+func (a *Authenticator) AuthenticateHTTP(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	s := a.current()
+	if s.engine.HealthPath != "" && r.URL.Path == s.engine.HealthPath {
+		w.WriteHeader(http.StatusOK)
+		return nil, nil
+	}
+	if s.engine.skip(r) {
+		return r.WithContext(context.WithValue(r.Context(), scopeContextKey, s)), nil
+	}
+	req, _, err := a.verify(s, w, r)
+	s.engine.stats.record(err)
+	s.engine.logFailure(req, err)
+	if err != nil {
+		removeForm(req)
+		if s.engine.status(err) == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", s.engine.challenge(err))
+		}
+	}
+	return req, err
+}
+
+// verify runs the verification of the request in the scope, returning the request carrying the auth data
+// along with the data itself, or the latest request along with the error. The response writer, if any,
+// gets the headers of the response, e.g. the request ID.
+func (a *Authenticator) verify(s *scope, w http.ResponseWriter, r *http.Request) (*http.Request, any, error) {
+	engine, app, cli := s.engine, s.app, s.client
+	r = r.WithContext(context.WithValue(r.Context(), scopeContextKey, s))
+	r = engine.withRequestID(w, r)
+	if engine.PreVerify != nil {
+		if err := engine.PreVerify(r); err != nil {
+			return r, nil, withStatus(http.StatusForbidden, err)
+		}
+	}
+	r, err := engine.resolveTenant(r)
+	if err != nil {
+		return r, nil, err
+	}
+	start := engine.Now()
+	data, r, err := engine.traced(r, app, cli)
+	if engine.OnVerify != nil {
+		engine.OnVerify(outcome(err), engine.Now().Sub(start))
+	}
+	if err != nil {
+		if engine.Optional && anonymous(err) {
+			return r, nil, nil
+		}
+		return r, nil, err
+	}
+	engine.complete(data, start)
+	engine.normalizeClaims(data)
+	if err = engine.checkLifetime(data); err != nil {
+		return r, nil, err
+	}
+	if err = engine.checkTenant(r, data); err != nil {
+		return r, nil, err
+	}
+	if err = engine.checkProject(data); err != nil {
+		return r, nil, err
+	}
+	if err = engine.checkAudience(r, data); err != nil {
+		return r, nil, err
+	}
+	if err = engine.verifyBinding(r, data); err != nil {
+		return r, nil, err
+	}
+	if r, err = engine.loadData(r, data); err != nil {
+		return r, nil, err
+	}
+	if r, err = engine.resolvePermissions(r, data); err != nil {
+		return r, nil, err
+	}
+	r = engine.resolveUpgrade(r, data)
+	r = engine.withLogger(r, data)
+	req, err := engine.OnData(r, data)
+	if err != nil {
+		return r, nil, err
+	}
+	a.markActive(req.Context(), data)
+	if w != nil && engine.PrivateCacheControl {
+		w.Header().Set("Cache-Control", engine.CacheControl)
+	}
+	return req, data, nil
+}
+
+// serve calls the handler, cleaning up the multipart form of the request afterwards.
+func serve(h http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	h.ServeHTTP(w, r)
+	removeForm(r)
+}
+
+// removeForm removes the temporary files of the multipart form parsed by `FromForm`, if any:
+// the server only cleans up the one of the original request, not of its copies.
+func removeForm(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
+// anonymous reports whether the error is due to a missing or invalid token, letting the `Engine.Optional` requests through.
+func anonymous(err error) bool {
+	var ue *unauthenticatedError
+	return errors.As(err, &ue) && StatusCode(err) == http.StatusUnauthorized
+}
+
+// Handler is like `Wrap`, but for an `http.Handler`.
+func (a *Authenticator) Handler(h http.Handler) http.Handler {
+	return a.Wrap(h.ServeHTTP)
+}
+
+// authenticate runs the `Engine.OnAuth` func, bounded by the `Engine.VerifyTimeout`, and by the `Engine.DefaultTimeout`
+// if the request has no deadline. It returns the request with its original context, carrying the changes made by `OnAuth`,
+// e.g. to the body.
+func (e *Engine) authenticate(r *http.Request, app *firebase.App, client *auth.Client) (any, *http.Request, error) {
+	ctx := r.Context()
+	timeout := e.VerifyTimeout
+	if _, ok := ctx.Deadline(); !ok && e.DefaultTimeout > 0 && (timeout <= 0 || e.DefaultTimeout < timeout) {
+		timeout = e.DefaultTimeout
+	}
+	if timeout <= 0 {
+		data, err := e.OnAuth(r, app, client)
+		return data, r, err
+	}
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	rt := r.WithContext(tctx)
+	data, err := e.OnAuth(rt, app, client)
+	if err != nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		err = unavailable(fmt.Errorf("%w: %w", ErrTimeout, err))
+	}
+	return data, rt.WithContext(ctx), err
+}
+
+func newEngine(opts ...Option) *Engine {
 	engine := &Engine{}
+	defaultsMu.RLock()
+	for _, opt := range defaults {
+		opt(engine)
+	}
+	defaultsMu.RUnlock()
 	for _, opt := range opts {
 		opt(engine)
 	}
+	return withEngineDefaults(engine)
+}
+
+// withEngineDefaults sets the unset fields of the Engine to their default values.
+func withEngineDefaults(engine *Engine) *Engine {
 	if engine.NewApp == nil {
 		engine.NewApp = defaultNewApp
 	}
@@ -149,27 +788,94 @@ func Auth(ctx context.Context, opts ...Option) (func(http.HandlerFunc) http.Hand
 	if engine.OnErr == nil {
 		engine.OnErr = defaultOnErr
 	}
-	app, err := engine.NewApp(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("fauth: error initializing firebase: %w", err)
+	if engine.AuthScheme == "" {
+		engine.AuthScheme = "bearer"
+	}
+	if engine.SessionCookieName == "" {
+		engine.SessionCookieName = defaultSessionCookieName
+	}
+	if engine.SessionLifetime == 0 {
+		engine.SessionLifetime = defaultSessionLifetime
 	}
-	cli, err := app.Auth(ctx)
+	if engine.UserCacheSize == 0 {
+		engine.UserCacheSize = defaultUserCacheSize
+	}
+	if engine.TokenCacheSize == 0 {
+		engine.TokenCacheSize = defaultTokenCacheSize
+	}
+	if engine.CacheControl == "" {
+		engine.CacheControl = defaultCacheControl
+	}
+	if engine.RolesClaim == "" {
+		engine.RolesClaim = defaultRolesClaim
+	}
+	if engine.MaxBodyBytes == 0 {
+		engine.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if engine.Now == nil {
+		engine.Now = time.Now
+	}
+	return engine
+}
+
+// loadData runs the data loading hooks, returning an error only if `OnDataFatal` is set.
+func (e *Engine) loadData(r *http.Request, data any) (*http.Request, error) {
+	token, ok := tokenOf(data)
+	if !ok || token == nil || e.LoadProfile == nil {
+		return r, nil
+	}
+	profile, err := e.LoadProfile(r.Context(), token.UID)
 	if err != nil {
-		return nil, fmt.Errorf("fauth: error initializing firebase auth: %w", err)
-	}
-	return func(h http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			data, err := engine.OnAuth(r, app, cli)
-			if err != nil {
-				engine.OnErr(w, r, app, cli, err)
-				return
-			}
-			req, err := engine.OnData(r, data)
-			if err != nil {
-				engine.OnErr(w, r, app, cli, err)
-				return
-			}
-			h.ServeHTTP(w, req)
+		if e.OnDataFatal {
+			return r, unavailable(fmt.Errorf("fauth: failed to load the profile: %w", err))
 		}
-	}, nil
+		return r, nil
+	}
+	return r.WithContext(WithProfile(r.Context(), profile)), nil
+}
+
+// scope holds the Engine serving the request along with its Firebase app and client.
+type scope struct {
+	engine *Engine
+	app    *firebase.App
+	client *auth.Client
+	users  *lruCache[*auth.UserRecord]
+	tokens *lruCache[cachedToken]
+	// firestore is the client created when `Engine.InjectFirestore` is set.
+	firestore *firestore.Client
+}
+
+const scopeContextKey contextKey = "scope"
+
+// defaultCacheControl keeps both the shared and the private caches from storing the authenticated responses.
+const defaultCacheControl = "private, no-store"
+
+// defaultScope is the scope outside of `Auth`, shared by all the calls. Its Engine ignores the `SetDefaults` options,
+// which configure the middleware, not the package funcs, e.g. `VerifyString`.
+var (
+	defaultScopeOnce sync.Once
+	defaultScope     *scope
+)
+
+// scopeFrom returns the scope the request is served in, or the default one outside of `Auth`.
+func scopeFrom(ctx context.Context) *scope {
+	if s, ok := ctx.Value(scopeContextKey).(*scope); ok {
+		return s
+	}
+	defaultScopeOnce.Do(func() {
+		defaultScope = &scope{engine: withEngineDefaults(&Engine{})}
+	})
+	return defaultScope
+}
+
+// Now returns the current time according to the `Engine.Now` clock of the request scope.
+// Outside of `Auth`, it returns `time.Now()`.
+func Now(ctx context.Context) time.Time {
+	return scopeFrom(ctx).engine.Now()
+}
+
+// fail passes the error to the `Engine.OnErr` func of the request scope.
+func fail(w http.ResponseWriter, r *http.Request, err error) {
+	s := scopeFrom(r.Context())
+	s.engine.OnErr(w, r, s.app, s.client, err)
 }
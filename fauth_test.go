@@ -2,13 +2,21 @@ package fauth_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
 	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
 )
 
 func TestParseBearerValid(t *testing.T) {
@@ -16,11 +24,18 @@ func TestParseBearerValid(t *testing.T) {
 		"bearer token",
 		"BEARER token",
 		"bEaReR token",
+		`Bearer token, realm="api"`,
+		`Bearer token,realm="a, \"b\"" , scope=read`,
+		"Bearer token,",
 	}
 	for _, h := range header {
-		if _, err := fauth.ParseBearer(h); err != nil {
+		token, err := fauth.ParseBearer(h)
+		if err != nil {
 			t.Fatal(err)
 		}
+		if token != "token" {
+			t.Fatalf("invalid token: %s", token)
+		}
 	}
 }
 
@@ -31,6 +46,11 @@ func TestParseBearerInvalid(t *testing.T) {
 		"bearer ",
 		"bearer  ",
 		"welcome to the jungle",
+		"bearer token realm",
+		"bearer token, realm",
+		`bearer token, realm="api`,
+		"bearer token, realm=api scope",
+		"bearer , realm=api",
 	}
 	for _, h := range header {
 		if _, err := fauth.ParseBearer(h); err == nil {
@@ -39,6 +59,179 @@ func TestParseBearerInvalid(t *testing.T) {
 	}
 }
 
+func TestParseAuthorization(t *testing.T) {
+	valid := []string{"JWT token", "jwt token", "jWt token, realm=api"}
+	for _, h := range valid {
+		if _, err := fauth.ParseAuthorization(h, "JWT"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	invalid := []string{"Bearer token", "JWT", "JWTtoken"}
+	for _, h := range invalid {
+		if _, err := fauth.ParseAuthorization(h, "JWT"); err == nil {
+			t.Fatalf("%s should be an invalid header", h)
+		}
+	}
+	if _, err := fauth.ParseBearer("JWT token"); err == nil {
+		t.Fatal("ParseBearer should only accept the bearer scheme")
+	}
+}
+
+func TestAuthScheme(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.AuthScheme = "JWT"
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	jwt := sign(map[string]any{"sub": "uid"})
+
+	for header, code := range map[string]int{
+		"JWT " + jwt:    http.StatusOK,
+		"jwt " + jwt:    http.StatusOK,
+		"Bearer " + jwt: http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("Authorization", header)
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", header[:6], code, w.Code)
+		}
+	}
+}
+
+func TestRealm(t *testing.T) {
+	tests := []struct {
+		realm     string
+		scheme    string
+		challenge string
+	}{
+		{"", "", `Bearer error="invalid_token", error_description="invalid token"`},
+		{"api", "", `Bearer realm="api", error="invalid_token", error_description="invalid token"`},
+		{`my "api"`, "JWT", `JWT realm="my \"api\"", error="invalid_request", error_description="invalid header"`},
+	}
+	for _, tt := range tests {
+		withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+			e.Realm = tt.realm
+			e.AuthScheme = tt.scheme
+		})
+		w := serveBearer(withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {}), "invalid")
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("invalid status: %d", w.Code)
+		}
+		if c := w.Header().Get("WWW-Authenticate"); c != tt.challenge {
+			t.Fatalf("expected %q, got %q", tt.challenge, c)
+		}
+	}
+
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.Realm = "api"
+	})
+	w := httptest.NewRecorder()
+	withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})(w, httptest.NewRequest("", "http://www.example.com", nil))
+	if c := w.Header().Get("WWW-Authenticate"); c != `Bearer realm="api"` {
+		t.Fatalf("missing token: expected a bare challenge, got %q", c)
+	}
+}
+
+func TestDebug(t *testing.T) {
+	for debug, body := range map[bool]string{false: "", true: "token expired\n"} {
+		withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+			e.Debug = debug
+		})
+		jwt := sign(map[string]any{"sub": "uid", "exp": time.Now().Add(-time.Minute).Unix()})
+		w := serveBearer(withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {}), jwt)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("invalid status: %d", w.Code)
+		}
+		if w.Body.String() != body {
+			t.Fatalf("debug %t: expected %q, got %q", debug, body, w.Body.String())
+		}
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	fauth.SetDefaults(func(e *fauth.Engine) {
+		e.Realm = "default"
+		e.AuthScheme = "JWT"
+	})
+	defer fauth.SetDefaults()
+
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.Realm = "api"
+	})
+	w := serveBearer(withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {}), "invalid")
+	if c := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(c, `JWT realm="api",`) {
+		t.Fatalf("the per-call options should take precedence over the defaults, got: %s", c)
+	}
+
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	if token, err := fauth.ExtractToken(r); token != "token" || err != nil {
+		t.Fatalf("the defaults shouldn't apply outside of the middleware, got: %s, %v", token, err)
+	}
+}
+
+func TestPreVerify(t *testing.T) {
+	verified := false
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.PreVerify = func(r *http.Request) error {
+			if r.Header.Get("X-Blocked") != "" {
+				return errors.New("blocked")
+			}
+			return nil
+		}
+		onAuth := e.OnAuth
+		e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			verified = true
+			return onAuth(r, app, client)
+		}
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	jwt := sign(map[string]any{"sub": "uid"})
+
+	if w := serveBearer(h, jwt); w.Code != http.StatusOK || !verified {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+
+	verified = false
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Bearer "+jwt)
+	r.Header.Set("X-Blocked", "true")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+	if verified {
+		t.Fatal("the token shouldn't be verified")
+	}
+}
+
+func TestHealthPath(t *testing.T) {
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.HealthPath = "/healthz"
+	})
+	called := false
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	for path, code := range map[string]int{
+		"/healthz":   http.StatusOK,
+		"/healthz/x": http.StatusUnauthorized,
+		"/":          http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com"+path, nil))
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+	if called {
+		t.Fatal("the handler shouldn't be called")
+	}
+}
+
 func TestAuthData(t *testing.T) {
 	ctx := context.Background()
 	str := "dummy data"
@@ -67,6 +260,62 @@ func liveTestSetup(t *testing.T, f func(w *httptest.ResponseRecorder, r *http.Re
 	f(w, r, jwt)
 }
 
+var (
+	testKey     *rsa.PrivateKey
+	testKeyOnce sync.Once
+)
+
+// offlineAuth returns a middleware func verifying the tokens signed by the returned func.
+func offlineAuth(t *testing.T, opts ...fauth.Option) (func(http.HandlerFunc) http.HandlerFunc, func(claims map[string]any) string) {
+	t.Helper()
+
+	testKeyOnce.Do(func() {
+		var err error
+		if testKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic(err)
+		}
+	})
+	opts = append([]fauth.Option{fauthtest.NewVerifier(&testKey.PublicKey).Option()}, opts...)
+	withFirebaseAuth, err := fauth.Auth(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return withFirebaseAuth, func(claims map[string]any) string {
+		jwt, err := fauthtest.NewSignedToken(claims, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return jwt
+	}
+}
+
+// fakeEmulator stands in for the Auth Emulator, answering the API methods, e.g. `accounts:lookup`,
+// with the canned JSON responses.
+func fakeEmulator(t *testing.T, responses map[string]string) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for method, resp := range responses {
+			if strings.HasSuffix(r.URL.Path, method) {
+				_, _ = w.Write([]byte(resp))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+}
+
+// serveBearer serves a request carrying the bearer token, returning the recorded response.
+func serveBearer(h http.HandlerFunc, jwt string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Bearer "+jwt)
+	h.ServeHTTP(w, r)
+	return w
+}
+
 func TestVerifyIDToken(t *testing.T) {
 	liveTestSetup(t, func(w *httptest.ResponseRecorder, r *http.Request, jwt string) {
 		withFirebaseAuth, err := fauth.Auth(context.Background())
@@ -124,3 +373,344 @@ func TestVerifyIDTokenAndCheckRevoked(t *testing.T) {
 		}
 	})
 }
+
+func TestVerifyRequest(t *testing.T) {
+	ctx := context.Background()
+	app, err := fauthtest.NewApp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	if _, err := fauth.VerifyRequest(r, client); !errors.Is(err, fauth.ErrNoToken) {
+		t.Fatalf("expected ErrNoToken, got: %v", err)
+	}
+
+	r.Header.Set("Authorization", "Bearer garbage")
+	if _, err := fauth.VerifyRequest(r, client); !errors.Is(err, fauth.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got: %v", err)
+	}
+	if _, err := fauth.VerifyRequestAndCheckRevoked(r, client); !errors.Is(err, fauth.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got: %v", err)
+	}
+}
+
+func TestVerifyString(t *testing.T) {
+	fakeEmulator(t, map[string]string{"accounts:lookup": `{"users":[{"localId":"uid","validSince":"0"}]}`})
+	ctx := context.Background()
+	app, err := fauthtest.NewApp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := fauth.VerifyString(ctx, client, fauthtest.EmulatorToken("uid"))
+	if err != nil || token.UID != "uid" {
+		t.Fatalf("invalid token: %v, %v", token, err)
+	}
+	if _, err := fauth.VerifyString(ctx, client, ""); !errors.Is(err, fauth.ErrNoToken) {
+		t.Fatalf("expected ErrNoToken, got: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	go func() {
+		_, _ = w.WriteString("  " + fauthtest.EmulatorToken("uid") + "\n")
+		_ = w.Close()
+	}()
+	if token, err := fauth.VerifyStdin(ctx, client); err != nil || token.UID != "uid" {
+		t.Fatalf("invalid stdin token: %v, %v", token, err)
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	_, sign := offlineAuth(t)
+	withFirebaseAuth, err := fauth.AuthHandler(context.Background(), fauthtest.NewVerifier(&testKey.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uid string
+	h := withFirebaseAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		uid = token.UID
+	}))
+	if w := serveBearer(h.ServeHTTP, sign(map[string]any{"sub": "uid"})); w.Code != http.StatusOK || uid != "uid" {
+		t.Fatalf("invalid status: %d, uid: %s", w.Code, uid)
+	}
+	if w := serveBearer(h.ServeHTTP, "invalid"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	var deadline bool
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.DefaultTimeout = 10 * time.Millisecond
+		e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			_, deadline = r.Context().Deadline()
+			if r.Header.Get("X-Hang") != "" {
+				<-r.Context().Done()
+				return nil, r.Context().Err()
+			}
+			return &auth.Token{UID: "uid"}, nil
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handlerDeadline bool
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		_, handlerDeadline = r.Context().Deadline()
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com", nil))
+	if w.Code != http.StatusOK || !deadline || handlerDeadline {
+		t.Fatalf("the timeout should bound the verification only: %d, %t, %t", w.Code, deadline, handlerDeadline)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("X-Hang", "true")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("hung verifications should time out, got: %d", w.Code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com", nil).WithContext(ctx))
+	if !handlerDeadline {
+		t.Fatal("the deadline of the caller should be kept")
+	}
+}
+
+func TestVerifyTimeout(t *testing.T) {
+	var deadline time.Time
+	newHandler := func(opt fauth.Option) http.HandlerFunc {
+		withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+			e.NewApp = fauthtest.NewApp
+			e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+				deadline, _ = r.Context().Deadline()
+				if r.Header.Get("X-Hang") != "" {
+					<-r.Context().Done()
+					return nil, r.Context().Err()
+				}
+				return &auth.Token{UID: "uid"}, nil
+			}
+		}, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	}
+	tests := []struct {
+		opt      fauth.Option
+		deadline time.Duration
+		timeout  time.Duration
+	}{
+		{func(e *fauth.Engine) {}, 0, 0},
+		{func(e *fauth.Engine) { e.VerifyTimeout = time.Minute }, 0, time.Minute},
+		{func(e *fauth.Engine) { e.VerifyTimeout = time.Minute }, time.Hour, time.Minute},
+		{func(e *fauth.Engine) { e.VerifyTimeout = time.Minute }, time.Second, time.Second},
+		{func(e *fauth.Engine) { e.VerifyTimeout, e.DefaultTimeout = time.Minute, time.Second }, 0, time.Second},
+		{func(e *fauth.Engine) { e.VerifyTimeout, e.DefaultTimeout = time.Second, time.Minute }, 0, time.Second},
+		{func(e *fauth.Engine) { e.VerifyTimeout, e.DefaultTimeout = time.Minute, time.Second }, time.Hour, time.Minute},
+	}
+	for i, tt := range tests {
+		h := newHandler(tt.opt)
+		ctx := context.Background()
+		if tt.deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+			defer cancel()
+		}
+		deadline = time.Time{}
+		start := time.Now()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("", "http://www.example.com", nil).WithContext(ctx))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%d: unexpected status: %d", i, w.Code)
+		}
+		if tt.timeout == 0 {
+			if !deadline.IsZero() {
+				t.Fatalf("%d: unexpected deadline: %v", i, deadline)
+			}
+			continue
+		}
+		if d := deadline.Sub(start) - tt.timeout; d > time.Second/2 || d < -time.Second/2 {
+			t.Fatalf("%d: expected a timeout of %v, got %v", i, tt.timeout, deadline.Sub(start))
+		}
+	}
+
+	h := newHandler(func(e *fauth.Engine) { e.VerifyTimeout = 10 * time.Millisecond })
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("X-Hang", "true")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("X-Auth-Error") != "timeout" {
+		t.Fatalf("hung verifications should time out, got: %d, %q", w.Code, w.Header().Get("X-Auth-Error"))
+	}
+
+	if _, err := fauth.Auth(context.Background(), func(e *fauth.Engine) { e.VerifyTimeout = -time.Second }); err == nil {
+		t.Fatal("negative timeouts should be rejected")
+	}
+}
+
+func TestEngineNow(t *testing.T) {
+	frozen := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			return &auth.Token{UID: "uid"}, nil
+		}
+		e.Now = func() time.Time { return frozen }
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var now time.Time
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		now = fauth.Now(r.Context())
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "http://www.example.com", nil))
+
+	if !now.Equal(frozen) {
+		t.Fatalf("invalid time: %v", now)
+	}
+}
+
+func TestAuthenticatorReload(t *testing.T) {
+	ctx := context.Background()
+	fail := false
+	a, err := fauth.NewAuthenticator(ctx, func(e *fauth.Engine) {
+		e.NewApp = func(ctx context.Context) (*firebase.App, error) {
+			if fail {
+				return nil, errors.New("rotated key is invalid")
+			}
+			return fauthtest.NewApp(ctx)
+		}
+		e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			return app, nil
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var app any
+	h := a.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		app = fauth.AuthData(r.Context())
+	})
+	serve := func() any {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("", "http://www.example.com", nil))
+		return app
+	}
+
+	before := serve()
+	if err := a.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	after := serve()
+	if before == after {
+		t.Fatal("the app should have been reloaded")
+	}
+
+	fail = true
+	if err := a.Reload(ctx); err == nil {
+		t.Fatal("reload should fail")
+	}
+	if serve() != after {
+		t.Fatal("a failed reload should keep the current app")
+	}
+}
+
+func TestOptional(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.Optional = true
+		e.ProjectID = "other"
+	})
+	var (
+		called bool
+		data   any
+	)
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		called, data = true, fauth.AuthData(r.Context())
+	})
+	expired := time.Now().Add(-time.Minute).Unix()
+	tests := []struct {
+		jwt  string
+		code int
+	}{
+		{"", http.StatusOK},
+		{"garbage", http.StatusOK},
+		{sign(map[string]any{"sub": "uid", "exp": expired}), http.StatusOK},
+		{sign(map[string]any{"sub": "uid", "aud": "fauthtest"}), http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		called, data = false, nil
+		w := serveBearer(h, tt.jwt)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if called != (tt.code == http.StatusOK) || data != nil {
+			t.Fatalf("%d: unexpected call: %v, %v", i, called, data)
+		}
+	}
+
+	withOptionalAuth, sign := offlineAuth(t, func(e *fauth.Engine) { e.Optional = true })
+	h = withOptionalAuth(func(w http.ResponseWriter, r *http.Request) {
+		data = fauth.AuthData(r.Context())
+	})
+	if w := serveBearer(h, sign(map[string]any{"sub": "uid"})); w.Code != http.StatusOK || data == nil {
+		t.Fatalf("valid tokens should carry the auth data, got: %d", w.Code)
+	}
+	h = withOptionalAuth(fauth.RequireClaimPresent("tenant")(func(w http.ResponseWriter, r *http.Request) {}))
+	if w := serveBearer(h, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestPrivateCacheControl(t *testing.T) {
+	tests := []struct {
+		opt    fauth.Option
+		header string
+	}{
+		{func(e *fauth.Engine) {}, ""},
+		{func(e *fauth.Engine) { e.PrivateCacheControl = true }, "private, no-store"},
+		{func(e *fauth.Engine) { e.PrivateCacheControl, e.CacheControl = true, "private, max-age=60" }, "private, max-age=60"},
+	}
+	for i, tt := range tests {
+		withFirebaseAuth, sign := offlineAuth(t, tt.opt)
+		h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+		if w := serveBearer(h, sign(map[string]any{"sub": "uid"})); w.Header().Get("Cache-Control") != tt.header {
+			t.Fatalf("%d: expected %q, got %q", i, tt.header, w.Header().Get("Cache-Control"))
+		}
+		if w := serveBearer(h, "invalid"); w.Header().Get("Cache-Control") != "" {
+			t.Fatalf("%d: unexpected header on failure: %q", i, w.Header().Get("Cache-Control"))
+		}
+	}
+
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) { e.PrivateCacheControl = true })
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+	})
+	if w := serveBearer(h, sign(map[string]any{"sub": "uid"})); w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("handlers should override the header, got %q", w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,205 @@
+// Package fauthtest provides helpers for testing handlers protected by fauth without reaching Firebase.
+//
+// The tokens minted and verified by this package bypass Firebase entirely, they're meant for tests only.
+package fauthtest
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/internal/jwt"
+	"google.golang.org/api/option"
+)
+
+// ProjectID is the project ID of the Firebase app returned by `NewApp`.
+const ProjectID = "fauthtest"
+
+// NewApp returns a Firebase app which doesn't need any credentials.
+// Its Auth client can't verify real tokens, use a `Verifier` instead.
+func NewApp(ctx context.Context) (*firebase.App, error) {
+	return firebase.NewApp(ctx, &firebase.Config{ProjectID: ProjectID}, option.WithoutAuthentication())
+}
+
+// NewSignedToken mints an RS256 token with the given claims, signed by the key.
+// If absent, the `iat` and `exp` claims are set to now and one hour from now.
+func NewSignedToken(claims map[string]any, key *rsa.PrivateKey) (string, error) {
+	c := map[string]any{}
+	for k, v := range claims {
+		c[k] = v
+	}
+	now := time.Now()
+	if _, ok := c["iat"]; !ok {
+		c["iat"] = now.Unix()
+	}
+	if _, ok := c["exp"]; !ok {
+		c["exp"] = now.Add(time.Hour).Unix()
+	}
+	return jwt.Sign(nil, c, key)
+}
+
+// RequestWithExpiredToken returns a copy of the request carrying, in its Authorization header, a token minted
+// for the `UID` like by `NewSignedToken`, but that expired an hour ago, e.g. to assert the handling of expired tokens:
+//
+//	r, err := fauthtest.RequestWithExpiredToken(httptest.NewRequest("", "/", nil), key)
+//	...
+//	h.ServeHTTP(w, r) // 401 Unauthorized, with the expired error code
+//
+// It's meant for the offline `Verifier` only, configured with the public key of the key: neither Firebase
+// nor its emulator know the key, so they'd reject the token as invalid rather than expired.
+func RequestWithExpiredToken(r *http.Request, key *rsa.PrivateKey) (*http.Request, error) {
+	now := time.Now()
+	jwt, err := NewSignedToken(map[string]any{
+		"sub": UID,
+		"iat": now.Add(-2 * time.Hour).Unix(),
+		"exp": now.Add(-time.Hour).Unix(),
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+jwt)
+	return r, nil
+}
+
+// Verifier verifies the tokens minted by `NewSignedToken` offline, using the public key.
+//
+// Use its `Option` to exercise the full `fauth.Auth` middleware path in tests:
+//
+//	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+//	withFirebaseAuth, err := fauth.Auth(ctx, fauthtest.NewVerifier(&key.PublicKey).Option())
+//	...
+//	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+//	r.Header.Set("Authorization", "Bearer "+jwt)
+type Verifier struct {
+	// Key verifies the token signatures.
+	Key *rsa.PublicKey
+	// Now is the clock used to check the token expiry, defaulting to `time.Now`.
+	Now func() time.Time
+}
+
+// NewVerifier returns a Verifier configured with the public key.
+func NewVerifier(key *rsa.PublicKey) *Verifier {
+	return &Verifier{Key: key, Now: time.Now}
+}
+
+// VerifyIDToken verifies the signature and the expiry of the token, and decodes it.
+func (v *Verifier) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	t, err := jwt.Parse(idToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", fauth.ErrInvalidToken, err)
+	}
+	if err := t.Verify(v.Key); err != nil {
+		return nil, fmt.Errorf("%w: %w", fauth.ErrInvalidToken, err)
+	}
+	token := &auth.Token{}
+	if err := json.Unmarshal(t.Payload, token); err != nil {
+		return nil, fmt.Errorf("%w: invalid payload: %w", fauth.ErrInvalidToken, err)
+	}
+	if err := json.Unmarshal(t.Payload, &token.Claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid payload: %w", fauth.ErrInvalidToken, err)
+	}
+	for _, c := range []string{"iss", "aud", "exp", "iat", "sub", "uid"} {
+		delete(token.Claims, c)
+	}
+	if token.Subject == "" {
+		return nil, fmt.Errorf("%w: empty sub claim", fauth.ErrInvalidToken)
+	}
+	token.UID = token.Subject
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if token.Expires < now().Unix() {
+		return nil, fmt.Errorf("%w at: %d", fauth.ErrTokenExpired, token.Expires)
+	}
+	return token, nil
+}
+
+// OnAuth is an `Engine.OnAuth` func verifying the bearer token using the Verifier.
+func (v *Verifier) OnAuth(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	idToken, err := fauth.ExtractToken(r)
+	if err != nil {
+		return nil, err
+	}
+	return v.VerifyIDToken(r.Context(), idToken)
+}
+
+// Option configures the Engine to run offline, verifying tokens with the Verifier.
+func (v *Verifier) Option() fauth.Option {
+	return func(e *fauth.Engine) {
+		e.NewApp = NewApp
+		e.OnAuth = v.OnAuth
+	}
+}
+
+// FakeVerifier is a `fauth.Verifier` returning canned tokens by their raw JWT string, letting the middleware
+// run fully offline, whatever the shape of the tokens:
+//
+//	fake := &fauthtest.FakeVerifier{Tokens: map[string]*auth.Token{"alice-token": {UID: "alice"}}}
+//	withFirebaseAuth, err := fauth.Auth(ctx, fake.Option())
+//	...
+//	r.Header.Set("Authorization", "Bearer alice-token")
+type FakeVerifier struct {
+	// Tokens are the tokens returned by JWT string. Unknown JWTs are rejected with `fauth.ErrInvalidToken`.
+	Tokens map[string]*auth.Token
+	// Revoked are the JWTs rejected with `fauth.ErrTokenRevoked` by `VerifyIDTokenAndCheckRevoked`.
+	Revoked map[string]bool
+}
+
+// VerifyIDToken returns a copy of the token of the JWT.
+func (f *FakeVerifier) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	token, ok := f.Tokens[idToken]
+	if !ok || token == nil {
+		return nil, fmt.Errorf("%w: unknown token", fauth.ErrInvalidToken)
+	}
+	t := *token
+	if token.Claims != nil {
+		t.Claims = make(map[string]any, len(token.Claims))
+		for k, v := range token.Claims {
+			t.Claims[k] = v
+		}
+	}
+	return &t, nil
+}
+
+// VerifyIDTokenAndCheckRevoked is like `VerifyIDToken`, but rejects the `Revoked` JWTs.
+func (f *FakeVerifier) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error) {
+	if f.Revoked[idToken] {
+		return nil, fmt.Errorf("%w: revoked", fauth.ErrTokenRevoked)
+	}
+	return f.VerifyIDToken(ctx, idToken)
+}
+
+// Option configures the Engine to run offline, verifying the tokens with the FakeVerifier.
+func (f *FakeVerifier) Option() fauth.Option {
+	return func(e *fauth.Engine) {
+		e.NewApp = NewApp
+		e.Verifier = f
+	}
+}
+
+// EmulatorToken mints an unsigned token for the user with the UID, issued for the `ProjectID` project,
+// which the Auth Emulator, or a fake of it, accepts. It's meant for the tests running against an emulator.
+func EmulatorToken(uid string) string {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]any{"alg": "none", "typ": "JWT"})
+	payload, _ := json.Marshal(map[string]any{
+		"aud":       ProjectID,
+		"iss":       "https://securetoken.google.com/" + ProjectID,
+		"sub":       uid,
+		"auth_time": now.Unix(),
+		// A second ahead, so the token isn't considered issued before the user was created.
+		"iat": now.Unix() + 1,
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(header) + "." + enc.EncodeToString(payload) + "."
+}
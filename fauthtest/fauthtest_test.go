@@ -0,0 +1,183 @@
+package fauthtest_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withFirebaseAuth, err := fauth.Auth(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uid, role string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		uid = token.UID
+		claims, _ := fauth.ClaimsFromContext(r.Context())
+		role, _ = claims.String("role")
+	})
+
+	serve := func(claims map[string]any, key *rsa.PrivateKey) int {
+		jwt, err := fauthtest.NewSignedToken(claims, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("Authorization", "Bearer "+jwt)
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(map[string]any{"sub": "uid", "role": "admin"}, key); code != http.StatusOK {
+		t.Fatalf("invalid status: %d", code)
+	}
+	if uid != "uid" || role != "admin" {
+		t.Fatalf("invalid auth data: %s, %s", uid, role)
+	}
+
+	expired := time.Now().Add(-time.Minute).Unix()
+	tests := []map[string]any{
+		{"sub": "uid", "exp": expired},
+		{"role": "admin"},
+	}
+	for _, claims := range tests {
+		if code := serve(claims, key); code != http.StatusUnauthorized {
+			t.Fatalf("%v: invalid status: %d", claims, code)
+		}
+	}
+	if code := serve(map[string]any{"sub": "uid"}, other); code != http.StatusUnauthorized {
+		t.Fatalf("wrong key: invalid status: %d", code)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	var uid string
+	srv, client := fauthtest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		uid = token.UID
+	})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("invalid status: %d", resp.StatusCode)
+	}
+	if uid != fauthtest.UID {
+		t.Fatalf("invalid uid: %s", uid)
+	}
+
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("requests without the token should be rejected, got: %d", resp.StatusCode)
+	}
+}
+
+func TestFakeVerifier(t *testing.T) {
+	fake := &fauthtest.FakeVerifier{
+		Tokens: map[string]*auth.Token{
+			"alice": {UID: "alice", Claims: map[string]any{"role": "admin"}},
+			"bob":   {UID: "bob", Claims: map[string]any{}},
+		},
+		Revoked: map[string]bool{"bob": true},
+	}
+	var _ fauth.Verifier = fake
+	withFirebaseAuth, err := fauth.Auth(context.Background(), fake.Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	withRevocationCheck, err := fauth.Auth(context.Background(), fake.Option(), func(e *fauth.Engine) {
+		e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uid string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		uid = token.UID
+		token.Claims["role"] = "tampered"
+	}
+	tests := []struct {
+		h    http.HandlerFunc
+		jwt  string
+		code int
+		err  string
+	}{
+		{withFirebaseAuth(handler), "alice", http.StatusOK, ""},
+		{withFirebaseAuth(handler), "bob", http.StatusOK, ""},
+		{withFirebaseAuth(handler), "eve", http.StatusUnauthorized, "invalid_token"},
+		{withRevocationCheck(handler), "alice", http.StatusOK, ""},
+		{withRevocationCheck(handler), "bob", http.StatusUnauthorized, "revoked"},
+	}
+	for i, tt := range tests {
+		uid = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r.Header.Set("Authorization", "Bearer "+tt.jwt)
+		tt.h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+		if tt.code == http.StatusOK && uid != tt.jwt {
+			t.Fatalf("%d: expected %s, got %s", i, tt.jwt, uid)
+		}
+	}
+	if fake.Tokens["alice"].Claims["role"] != "admin" {
+		t.Fatal("the canned tokens shouldn't be altered")
+	}
+}
+
+func TestRequestWithExpiredToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFirebaseAuth, err := fauth.Auth(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	orig := httptest.NewRequest("", "http://www.example.com", nil)
+	r, err := fauthtest.RequestWithExpiredToken(orig, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orig.Header.Get("Authorization") != "" {
+		t.Fatal("the original request shouldn't be modified")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("X-Auth-Error") != "expired" {
+		t.Fatalf("invalid response: %d, %s", w.Code, w.Header().Get("X-Auth-Error"))
+	}
+}
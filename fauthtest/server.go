@@ -0,0 +1,80 @@
+package fauthtest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+// UID is the UID of the user the client returned by `NewServer` authenticates as.
+const UID = "fauthtest-user"
+
+// emulatorHostEnv is the environment variable the Firebase Admin SDK reads the Auth Emulator host from.
+const emulatorHostEnv = "FIREBASE_AUTH_EMULATOR_HOST"
+
+// NewServer starts an `httptest.Server` serving the handler behind the `fauth.Auth` middleware, configured
+// against the Firebase Auth Emulator, and returns it along with a client attaching a freshly minted token
+// of the `UID` user to every request. The server is closed once the test completes:
+//
+//	srv, client := fauthtest.NewServer(t, handler)
+//	resp, err := client.Get(srv.URL)
+//
+// The emulator is read from the `FIREBASE_AUTH_EMULATOR_HOST` variable, the test is skipped when it isn't set
+// or reachable. The options are applied after the ones wiring the emulator.
+func NewServer(t testing.TB, handler http.HandlerFunc, opts ...fauth.Option) (*httptest.Server, *http.Client) {
+	t.Helper()
+
+	host := os.Getenv(emulatorHostEnv)
+	if host == "" {
+		t.Skipf("%s isn't set", emulatorHostEnv)
+	}
+	conn, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("the Auth Emulator isn't reachable at %s: %v", host, err)
+	}
+	_ = conn.Close()
+
+	ctx := context.Background()
+	app, err := NewApp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateUser(ctx, (&auth.UserToCreate{}).UID(UID)); err != nil && !auth.IsUIDAlreadyExists(err) {
+		t.Fatalf("failed to create the emulator user: %v", err)
+	}
+
+	withFirebaseAuth, err := fauth.Auth(ctx, append([]fauth.Option{func(e *fauth.Engine) {
+		e.NewApp = NewApp
+	}}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(withFirebaseAuth(handler))
+	t.Cleanup(srv.Close)
+
+	c := srv.Client()
+	c.Transport = &bearerTransport{base: c.Transport}
+	return srv, c
+}
+
+// bearerTransport attaches a freshly minted emulator token to the requests.
+type bearerTransport struct {
+	base http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+EmulatorToken(UID))
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,38 @@
+package fauth
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+)
+
+// App returns the Firebase app of the Engine serving the request, saving the handlers from initializing their own,
+// e.g. to get its Cloud Messaging client. It reports false outside of `Auth`.
+func App(ctx context.Context) (*firebase.App, bool) {
+	s, ok := ctx.Value(scopeContextKey).(*scope)
+	if !ok || s.app == nil {
+		return nil, false
+	}
+	return s.app, true
+}
+
+// FirestoreFromContext returns the Firestore client of the Engine serving the request, created along with its
+// Firebase app when `Engine.InjectFirestore` is set, so the handlers don't create one per request:
+//
+//	fs, ok := fauth.FirestoreFromContext(r.Context())
+//	if !ok {
+//		...
+//	}
+//	token, _ := fauth.AuthToken(r.Context())
+//	doc, err := fs.Collection("users").Doc(token.UID).Get(r.Context())
+//
+// The client is shared by all the requests, don't close it. It reports false outside of `Auth`
+// or when `Engine.InjectFirestore` isn't set.
+func FirestoreFromContext(ctx context.Context) (*firestore.Client, bool) {
+	s, ok := ctx.Value(scopeContextKey).(*scope)
+	if !ok || s.firestore == nil {
+		return nil, false
+	}
+	return s.firestore, true
+}
@@ -0,0 +1,99 @@
+package fauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInjectFirestore(t *testing.T) {
+	if _, ok := fauth.App(context.Background()); ok {
+		t.Fatal("no app should be found outside of Auth")
+	}
+	if _, ok := fauth.FirestoreFromContext(context.Background()); ok {
+		t.Fatal("no client should be found outside of Auth")
+	}
+
+	// The client connects lazily, so the emulator needn't be running.
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inject := range []bool{false, true} {
+		a, err := fauth.NewAuthenticator(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option(), func(e *fauth.Engine) {
+			e.InjectFirestore = inject
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var hasApp, hasFirestore bool
+		h := a.Wrap(func(w http.ResponseWriter, r *http.Request) {
+			_, hasApp = fauth.App(r.Context())
+			_, hasFirestore = fauth.FirestoreFromContext(r.Context())
+		})
+		jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := serveBearer(h, jwt); w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: %d", w.Code)
+		}
+		if !hasApp || hasFirestore != inject {
+			t.Fatalf("%t: unexpected app %t or firestore %t", inject, hasApp, hasFirestore)
+		}
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestInjectFirestoreReload(t *testing.T) {
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fauth.NewAuthenticator(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option(), func(e *fauth.Engine) {
+		e.InjectFirestore = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fs *firestore.Client
+	h := a.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		fs, _ = fauth.FirestoreFromContext(r.Context())
+	})
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveBearer(h, jwt)
+	replaced := fs
+	if err := a.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	serveBearer(h, jwt)
+	if fs == replaced {
+		t.Fatal("the reload should create a new client")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close doesn't wait for the grace period of the replaced client.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := replaced.Collection("users").Doc("uid").Get(ctx); status.Code(err) != codes.Canceled {
+		t.Fatalf("the replaced client should be closed, got: %v", err)
+	}
+}
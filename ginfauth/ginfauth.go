@@ -0,0 +1,70 @@
+// Package ginfauth adapts the fauth middleware to the Gin framework.
+//
+// It's a separate module, so the fauth package doesn't depend on Gin.
+package ginfauth
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenKey is the key the verified `*auth.Token` is stored under in the `gin.Context`.
+const TokenKey = "fauth.token"
+
+// Middleware returns a Gin middleware func verifying the request is coming from a valid Firebase user,
+// configured like `fauth.Auth`:
+//
+//	withFirebaseAuth, err := ginfauth.Middleware(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	r := gin.Default()
+//	r.GET("/private", withFirebaseAuth, handler)
+//
+// The verified token is stored in the `gin.Context` under the `TokenKey`, see `Token`, and in the request context,
+// where the fauth funcs, e.g. `fauth.AuthToken`, read it from. Failures abort the request with the status code
+// of the error, see `fauth.StatusCode`, e.g. 401 Unauthorized, along with its code in the `X-Auth-Error` header;
+// the error itself is attached to the `gin.Context`. The `fauth.Engine` fields writing the response are honored,
+// e.g. the `HealthPath` or the `WWW-Authenticate` challenge, see `fauth.Authenticator.AuthenticateHTTP`.
+func Middleware(ctx context.Context, opts ...fauth.Option) (gin.HandlerFunc, error) {
+	a, err := fauth.NewAuthenticator(ctx, append([]fauth.Option{fauth.WithoutClose("ginfauth.Middleware")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return MiddlewareFor(a), nil
+}
+
+// MiddlewareFor is like `Middleware`, but uses the Authenticator, e.g. to share it with other handlers
+// or to reload its credentials.
+func MiddlewareFor(a *fauth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, err := a.AuthenticateHTTP(c.Writer, c.Request)
+		if err != nil {
+			if code := fauth.ErrorCode(err); code != "" {
+				c.Header("X-Auth-Error", code)
+			}
+			_ = c.Error(err)
+			c.AbortWithStatus(fauth.StatusCode(err))
+			return
+		}
+		if r == nil {
+			c.Abort()
+			return
+		}
+		c.Request = r
+		if token, ok := fauth.AuthToken(r.Context()); ok && token != nil {
+			c.Set(TokenKey, token)
+		}
+		c.Next()
+	}
+}
+
+// Token returns the verified token stored in the `gin.Context` by the middleware.
+func Token(c *gin.Context) (*auth.Token, bool) {
+	v, _ := c.Get(TokenKey)
+	token, ok := v.(*auth.Token)
+	return token, ok && token != nil
+}
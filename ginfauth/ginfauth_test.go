@@ -0,0 +1,113 @@
+package ginfauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"github.com/enfunc/fauth/ginfauth"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFirebaseAuth, err := ginfauth.Middleware(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/private", withFirebaseAuth, func(c *gin.Context) {
+		token, ok := ginfauth.Token(c)
+		if !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, token.UID)
+	})
+
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		header string
+		code   int
+		body   string
+		err    string
+	}{
+		{"Bearer " + jwt, http.StatusOK, "uid", ""},
+		{"Bearer garbage", http.StatusUnauthorized, "", "invalid_token"},
+		{"", http.StatusUnauthorized, "", "no_token"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/private", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Fatalf("%d: expected %d %q, got %d %q", i, tt.code, tt.body, w.Code, w.Body.String())
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+}
+
+func TestMiddlewareResponse(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFirebaseAuth, err := ginfauth.Middleware(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option(), func(e *fauth.Engine) {
+		e.HealthPath = "/healthz"
+		e.Realm = "api"
+		e.PrivateCacheControl = true
+		e.Skip = fauth.SkipPaths("/public")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(withFirebaseAuth)
+	r.GET("/*path", func(c *gin.Context) {
+		c.String(http.StatusOK, "handled")
+	})
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path, header string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := serve("/healthz", ""); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("the health path should be answered, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve("/public", ""); w.Code != http.StatusOK || w.Body.String() != "handled" {
+		t.Fatalf("the skipped paths should be let through, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve("/private", ""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+		t.Fatalf("the 401 responses should be challenged, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if w := serve("/private", "Bearer "+jwt); w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "private, no-store" {
+		t.Fatalf("the authenticated responses should be private, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+}
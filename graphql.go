@@ -0,0 +1,55 @@
+package fauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// GraphQLErrorHandler returns an `Engine.OnErr` func answering the failures the GraphQL way, with 200 OK
+// and a GraphQL response carrying the error in its `errors` array, for the GraphQL clients expecting them:
+//
+//	{"data":null,"errors":[{"message":"token expired","extensions":{"code":"UNAUTHENTICATED","reason":"expired"}}]}
+//
+// The `code` extension is the given code for the 401 Unauthorized failures, `UNAUTHENTICATED` if empty,
+// `FORBIDDEN` for the 403 Forbidden ones, and the status text in upper snake case for the other ones,
+// e.g. `SERVICE_UNAVAILABLE`. The `reason` extension is the code of the error, see `ErrorCode`, omitted for
+// the errors without one, which is also sent in the `X-Auth-Error` header. The message doesn't leak
+// the details of the failure.
+func GraphQLErrorHandler(code string) func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+	if code == "" {
+		code = "UNAUTHENTICATED"
+	}
+	return func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+		status := StatusCode(err)
+		ext := map[string]string{}
+		switch status {
+		case http.StatusUnauthorized:
+			ext["code"] = code
+		case http.StatusForbidden:
+			ext["code"] = "FORBIDDEN"
+		default:
+			ext["code"] = strings.ReplaceAll(strings.ToUpper(http.StatusText(status)), " ", "_")
+		}
+		if reason := ErrorCode(err); reason != "" {
+			ext["reason"] = reason
+			w.Header().Set("X-Auth-Error", reason)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		type gqlError struct {
+			Message    string            `json:"message"`
+			Extensions map[string]string `json:"extensions"`
+		}
+		_ = json.NewEncoder(w).Encode(struct {
+			Data   any        `json:"data"`
+			Errors []gqlError `json:"errors"`
+		}{
+			Errors: []gqlError{{Message: errorReason(err, status), Extensions: ext}},
+		})
+	}
+}
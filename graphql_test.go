@@ -0,0 +1,55 @@
+package fauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestGraphQLErrorHandler(t *testing.T) {
+	tests := []struct {
+		code    string
+		jwt     bool
+		claims  map[string]any
+		ext     string
+		reason  string
+		message string
+	}{
+		{"", false, nil, "UNAUTHENTICATED", "invalid_token", "invalid token"},
+		{"AUTH_REQUIRED", false, nil, "AUTH_REQUIRED", "invalid_token", "invalid token"},
+		{"", true, map[string]any{"sub": "uid"}, "FORBIDDEN", "missing_claim", "missing claim"},
+	}
+	for i, tt := range tests {
+		withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+			e.OnErr = fauth.GraphQLErrorHandler(tt.code)
+		})
+		h := withFirebaseAuth(fauth.RequireClaimPresent("role")(func(w http.ResponseWriter, r *http.Request) {}))
+		jwt := "invalid"
+		if tt.jwt {
+			jwt = sign(tt.claims)
+		}
+		w := serveBearer(h, jwt)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("%d: unexpected response: %d, %s", i, w.Code, w.Header().Get("Content-Type"))
+		}
+		var body struct {
+			Data   any `json:"data"`
+			Errors []struct {
+				Message    string            `json:"message"`
+				Extensions map[string]string `json:"extensions"`
+			} `json:"errors"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Errors) != 1 || body.Data != nil {
+			t.Fatalf("%d: unexpected body: %+v", i, body)
+		}
+		e := body.Errors[0]
+		if e.Extensions["code"] != tt.ext || e.Extensions["reason"] != tt.reason || e.Message != tt.message {
+			t.Fatalf("%d: unexpected error: %+v", i, e)
+		}
+	}
+}
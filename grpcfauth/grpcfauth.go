@@ -0,0 +1,43 @@
+// Package grpcfauth verifies the Firebase ID tokens carried by gRPC requests,
+// keeping the gRPC dependency out of the fauth package.
+package grpcfauth
+
+import (
+	"context"
+	"fmt"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"google.golang.org/grpc/metadata"
+)
+
+// VerifyMetadata verifies the bearer token of the `authorization` metadata, returning the token
+// and a context carrying it, to be retrieved with `fauth.AuthToken`. It's the gRPC analog of `fauth.VerifyRequest`,
+// meant for custom interceptors not running the `fauth.Engine` hooks like `UnaryServerInterceptor` does:
+//
+//	md, _ := metadata.FromIncomingContext(ctx)
+//	token, ctx, err := grpcfauth.VerifyMetadata(ctx, client, md)
+//	if err != nil {
+//		return nil, grpcfauth.Status(err).Err()
+//	}
+//	return handler(ctx, req)
+//
+// Like `fauth.VerifyRequest`, it doesn't check whether the token has been revoked.
+func VerifyMetadata(ctx context.Context, client *auth.Client, md metadata.MD) (*auth.Token, context.Context, error) {
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, ctx, fauth.ErrNoToken
+	}
+	if len(values) > 1 {
+		return nil, ctx, fmt.Errorf("%w: multiple authorization values", fauth.ErrMalformedHeader)
+	}
+	jwt, err := fauth.ParseBearer(values[0])
+	if err != nil {
+		return nil, ctx, err
+	}
+	token, err := fauth.VerifyString(ctx, client, jwt)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return token, fauth.WithAuthData(ctx, token), nil
+}
@@ -0,0 +1,57 @@
+package grpcfauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"github.com/enfunc/fauth/grpcfauth"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestVerifyMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"users":[{"localId":"uid","validSince":"0"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	ctx := context.Background()
+	app, err := fauthtest.NewApp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, tctx, err := grpcfauth.VerifyMetadata(ctx, client, metadata.Pairs("authorization", "Bearer "+fauthtest.EmulatorToken("uid")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if t2, ok := fauth.AuthToken(tctx); !ok || token.UID != "uid" || t2 != token {
+		t.Fatalf("invalid token: %v", token)
+	}
+
+	tests := []struct {
+		md  metadata.MD
+		err error
+	}{
+		{metadata.MD{}, fauth.ErrNoToken},
+		{metadata.Pairs("authorization", "Basic dXNlcg=="), fauth.ErrMalformedHeader},
+		{metadata.Pairs("authorization", "Bearer a", "authorization", "Bearer b"), fauth.ErrMalformedHeader},
+		{metadata.Pairs("authorization", "Bearer invalid"), fauth.ErrInvalidToken},
+	}
+	for i, tt := range tests {
+		token, tctx, err := grpcfauth.VerifyMetadata(ctx, client, tt.md)
+		if !errors.Is(err, tt.err) || token != nil || tctx != ctx {
+			t.Fatalf("%d: expected %v, got %v", i, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package grpcfauth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/enfunc/fauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a unary interceptor verifying the calls are coming from valid Firebase users,
+// sharing the `fauth.Engine` configuration with the HTTP middleware:
+//
+//	opt := func(e *fauth.Engine) { e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked }
+//	unary, err := grpcfauth.UnaryServerInterceptor(ctx, opt)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	srv := grpc.NewServer(grpc.UnaryInterceptor(unary))
+//
+// The calls are verified like the HTTP requests by `fauth.Authenticator.Authenticate`, their metadata being
+// translated to the request headers, so the token is read from the `authorization` metadata and the `Engine` hooks
+// run as they do for HTTP. The handlers retrieve the auth data from their context, e.g. using `fauth.AuthToken`.
+// Failures are answered with the status returned by `Status`.
+func UnaryServerInterceptor(ctx context.Context, opts ...fauth.Option) (grpc.UnaryServerInterceptor, error) {
+	a, err := fauth.NewAuthenticator(ctx, append([]fauth.Option{fauth.WithoutClose("grpcfauth.UnaryServerInterceptor")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return UnaryInterceptor(a), nil
+}
+
+// StreamServerInterceptor is like `UnaryServerInterceptor`, but for the streaming calls.
+func StreamServerInterceptor(ctx context.Context, opts ...fauth.Option) (grpc.StreamServerInterceptor, error) {
+	a, err := fauth.NewAuthenticator(ctx, append([]fauth.Option{fauth.WithoutClose("grpcfauth.StreamServerInterceptor")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return StreamInterceptor(a), nil
+}
+
+// UnaryInterceptor is like `UnaryServerInterceptor`, but uses the Authenticator, e.g. to share it with
+// the HTTP middleware or to reload its credentials.
+func UnaryInterceptor(a *fauth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, a, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is like `StreamServerInterceptor`, but uses the Authenticator.
+func StreamInterceptor(a *fauth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), a, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of the stream with the one carrying the auth data.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate verifies the call, returning the context carrying the auth data.
+func authenticate(ctx context.Context, a *fauth.Authenticator, method string) (context.Context, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "invalid method")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, values := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		for _, v := range values {
+			r.Header.Add(k, v)
+		}
+	}
+	if authority := md.Get(":authority"); len(authority) > 0 {
+		r.Host = authority[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	r, err = a.Authenticate(r)
+	if err != nil {
+		return nil, Status(err).Err()
+	}
+	return r.Context(), nil
+}
+
+// Status returns the gRPC status of the fauth error, mapping its HTTP status code, see `fauth.StatusCode`,
+// e.g. 401 Unauthorized to `codes.Unauthenticated` and 403 Forbidden to `codes.PermissionDenied`.
+// Its message is the code of the error, see `fauth.ErrorCode`, leaving the details server-side.
+func Status(err error) *status.Status {
+	code := fauth.StatusCode(err)
+	msg := fauth.ErrorCode(err)
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	return status.New(grpcCode(code), msg)
+}
+
+func grpcCode(code int) codes.Code {
+	switch code {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if code >= 400 && code < 500 {
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
@@ -0,0 +1,108 @@
+package grpcfauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"github.com/enfunc/fauth/grpcfauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	unary, err := grpcfauth.UnaryServerInterceptor(ctx, fauthtest.NewVerifier(&key.PublicKey).Option(), func(e *fauth.Engine) {
+		e.OnAuth = fauth.RequireClaims(map[string]any{"admin": true})(e.OnAuth)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(claims map[string]any) string {
+		jwt, err := fauthtest.NewSignedToken(claims, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + jwt
+	}
+
+	tests := []struct {
+		md   metadata.MD
+		code codes.Code
+		msg  string
+	}{
+		{metadata.Pairs("authorization", sign(map[string]any{"sub": "uid", "admin": true})), codes.OK, ""},
+		{metadata.Pairs("authorization", sign(map[string]any{"sub": "uid"})), codes.PermissionDenied, "missing_claim"},
+		{metadata.Pairs("authorization", "Bearer garbage"), codes.Unauthenticated, "invalid_token"},
+		{metadata.MD{}, codes.Unauthenticated, "no_token"},
+	}
+	for i, tt := range tests {
+		var uid string
+		_, err := unary(metadata.NewIncomingContext(ctx, tt.md), nil, &grpc.UnaryServerInfo{FullMethod: "/svc.Service/Method"},
+			func(ctx context.Context, req any) (any, error) {
+				token, _ := fauth.AuthToken(ctx)
+				uid = token.UID
+				return nil, nil
+			})
+		if s := status.Convert(err); s.Code() != tt.code || err != nil && s.Message() != tt.msg {
+			t.Fatalf("%d: expected %v %q, got %v", i, tt.code, tt.msg, err)
+		}
+		if tt.code == codes.OK && uid != "uid" {
+			t.Fatalf("%d: invalid uid: %q", i, uid)
+		}
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	stream, err := grpcfauth.StreamServerInterceptor(ctx, fauthtest.NewVerifier(&key.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var uid string
+	ss := &serverStream{ctx: metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+jwt))}
+	err = stream(nil, ss, &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"}, func(srv any, ss grpc.ServerStream) error {
+		token, _ := fauth.AuthToken(ss.Context())
+		uid = token.UID
+		return nil
+	})
+	if err != nil || uid != "uid" {
+		t.Fatalf("unexpected result: %q, %v", uid, err)
+	}
+
+	ss = &serverStream{ctx: ctx}
+	err = stream(nil, ss, &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"}, func(srv any, ss grpc.ServerStream) error {
+		t.Fatal("the handler shouldn't be called")
+		return nil
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package fauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// Identity headers written by `WriteIdentityHeaders`.
+const (
+	HeaderUID           = "X-Auth-UID"
+	HeaderEmail         = "X-Auth-Email"
+	HeaderEmailVerified = "X-Auth-Email-Verified"
+	HeaderClaimPrefix   = "X-Auth-Claim-"
+)
+
+// IdentityHeaderOptions configures `WriteIdentityHeaders`.
+type IdentityHeaderOptions struct {
+	// Claims lists the custom claims forwarded as `X-Auth-Claim-<key>` headers.
+	// Nested claims can be referenced using the dot notation. Absent claims are skipped.
+	Claims []string
+}
+
+// WriteIdentityHeaders propagates the identity of the verified token to downstream services by setting
+// the `X-Auth-UID`, `X-Auth-Email` and `X-Auth-Email-Verified` headers, along with the claims listed in the options.
+// String claims are forwarded as they are, any other claim is JSON-encoded. A nil token is rejected
+// with an error wrapping `ErrNoToken`, leaving the headers alone.
+//
+// This is meant for service meshes verifying tokens at the edge. Downstream services must only trust
+// these headers when they come from the edge; the edge must therefore drop them from client requests,
+// e.g. using `StripIdentityHeaders`, before setting its own.
+func WriteIdentityHeaders(h http.Header, token *auth.Token, opts IdentityHeaderOptions) error {
+	if token == nil {
+		return errNoAuthToken
+	}
+	StripIdentityHeaders(h)
+	h.Set(HeaderUID, token.UID)
+	claims := Claims(token.Claims)
+	if email, ok := claims.String("email"); ok {
+		h.Set(HeaderEmail, email)
+	}
+	if verified, ok := claims.Bool("email_verified"); ok {
+		h.Set(HeaderEmailVerified, strconv.FormatBool(verified))
+	}
+	for _, key := range opts.Claims {
+		v, ok := claims.Get(key)
+		if !ok {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			h.Set(HeaderClaimPrefix+key, s)
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("fauth: failed to encode claim %s: %w", key, err)
+		}
+		h.Set(HeaderClaimPrefix+key, string(b))
+	}
+	return nil
+}
+
+// StripIdentityHeaders removes all the identity headers set by `WriteIdentityHeaders`.
+func StripIdentityHeaders(h http.Header) {
+	for key := range h {
+		if strings.HasPrefix(http.CanonicalHeaderKey(key), HeaderClaimPrefix) {
+			h.Del(key)
+		}
+	}
+	h.Del(HeaderUID)
+	h.Del(HeaderEmail)
+	h.Del(HeaderEmailVerified)
+}
@@ -0,0 +1,54 @@
+package fauth_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestWriteIdentityHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Auth-UID", "spoofed")
+	h.Set("X-Auth-Claim-Admin", "true")
+	h.Set("Accept", "*/*")
+
+	token := &auth.Token{UID: "uid", Claims: map[string]any{
+		"email":          "user@example.com",
+		"email_verified": true,
+		"plan":           "pro",
+		"org":            map[string]any{"id": 42.0},
+	}}
+	err := fauth.WriteIdentityHeaders(h, token, fauth.IdentityHeaderOptions{Claims: []string{"plan", "org.id", "org", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"X-Auth-UID":            "uid",
+		"X-Auth-Email":          "user@example.com",
+		"X-Auth-Email-Verified": "true",
+		"X-Auth-Claim-Plan":     "pro",
+		"X-Auth-Claim-Org.id":   "42",
+		"X-Auth-Claim-Org":      `{"id":42}`,
+		"X-Auth-Claim-Admin":    "",
+		"X-Auth-Claim-Missing":  "",
+		"Accept":                "*/*",
+	}
+	for k, v := range expected {
+		if got := h.Get(k); got != v {
+			t.Fatalf("%s: expected %q, got %q", k, v, got)
+		}
+	}
+
+	fauth.StripIdentityHeaders(h)
+	if len(h) != 1 {
+		t.Fatalf("identity headers should be stripped: %v", h)
+	}
+
+	if err := fauth.WriteIdentityHeaders(h, nil, fauth.IdentityHeaderOptions{}); !errors.Is(err, fauth.ErrNoToken) {
+		t.Fatalf("a nil token should be rejected, got: %v", err)
+	}
+}
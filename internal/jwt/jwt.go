@@ -0,0 +1,145 @@
+// Package jwt implements the subset of RS256 JSON Web Tokens needed by fauth and its test helpers.
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when the signature of the token doesn't match the key.
+var ErrInvalidSignature = errors.New("jwt: invalid signature")
+
+// Sign encodes the header and the claims, and signs them using RS256.
+// The `alg` and `typ` header fields are always set.
+func Sign(header, claims map[string]any, key *rsa.PrivateKey) (string, error) {
+	h := map[string]any{}
+	for k, v := range header {
+		h[k] = v
+	}
+	h["alg"] = "RS256"
+	h["typ"] = "JWT"
+	hb, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("jwt: failed to encode header: %w", err)
+	}
+	cb, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt: failed to encode claims: %w", err)
+	}
+	signed := encode(hb) + "." + encode(cb)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("jwt: failed to sign: %w", err)
+	}
+	return signed + "." + encode(sig), nil
+}
+
+// Token is a decoded, not yet verified, JWT.
+type Token struct {
+	Header  map[string]any
+	Payload []byte
+	signed  string
+	sig     []byte
+}
+
+// Parse decodes the token without verifying its signature.
+func Parse(token string) (*Token, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, errors.New("jwt: incorrect number of segments")
+	}
+	hb, err := decode(segments[0])
+	if err != nil {
+		return nil, fmt.Errorf("jwt: invalid header: %w", err)
+	}
+	t := &Token{signed: segments[0] + "." + segments[1]}
+	if err := json.Unmarshal(hb, &t.Header); err != nil {
+		return nil, fmt.Errorf("jwt: invalid header: %w", err)
+	}
+	if t.Payload, err = decode(segments[1]); err != nil {
+		return nil, fmt.Errorf("jwt: invalid payload: %w", err)
+	}
+	if t.sig, err = decode(segments[2]); err != nil {
+		return nil, fmt.Errorf("jwt: invalid signature: %w", err)
+	}
+	return t, nil
+}
+
+// KeyID returns the `kid` header field.
+func (t *Token) KeyID() string {
+	kid, _ := t.Header["kid"].(string)
+	return kid
+}
+
+// Verify checks the token is signed with the key using RS256.
+func (t *Token) Verify(key *rsa.PublicKey) error {
+	if alg, _ := t.Header["alg"].(string); alg != "RS256" {
+		return fmt.Errorf("jwt: unexpected algorithm: %s", alg)
+	}
+	digest := sha256.Sum256([]byte(t.signed))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], t.sig); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// ParseJWKS decodes the RSA keys of the JSON Web Key Set, by key ID. Keys of other types are ignored.
+func ParseJWKS(b []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("jwt: invalid key set: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid modulus of key %s: %w", k.Kid, err)
+		}
+		e, err := decode(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwt: invalid exponent of key %s", k.Kid)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// JWK encodes the public key as a JSON Web Key with the key ID.
+func JWK(kid string, key *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kid": kid,
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   encode(key.N.Bytes()),
+		"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
@@ -0,0 +1,101 @@
+package fauth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// ClientIP returns the IP address of the client, i.e. the remote address of the request or, when the request
+// comes through the `Engine.TrustedProxies`, the rightmost untrusted address of the `X-Forwarded-For` header.
+// The addresses set by the clients themselves, left of the ones appended by the trusted proxies, are never returned,
+// so the header can't be used to spoof the address. It reports false if the address can't be parsed.
+func ClientIP(r *http.Request) (net.IP, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, false
+	}
+	e := scopeFrom(r.Context()).engine
+	if !e.trusted(ip) {
+		return ip, true
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			return nil, false
+		}
+		if !e.trusted(hop) {
+			return hop, true
+		}
+		ip = hop
+	}
+	// All the hops are trusted, the leftmost one being the closest to the client.
+	return ip, true
+}
+
+// trusted reports whether the IP address belongs to one of the `Engine.TrustedProxies`.
+func (e *Engine) trusted(ip net.IP) bool {
+	for _, n := range e.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireIPAllowed returns a middleware func rejecting the request with 403 Forbidden, and `ErrIPNotAllowed`,
+// unless the IP address of the client, see `ClientIP`, belongs to one of the networks the getAllowed func returns
+// for the UID of the verified token, e.g. binding the high-privilege accounts to the office networks:
+//
+//	withOfficeIP := fauth.RequireIPAllowed(func(uid string) ([]net.IPNet, error) {
+//		return db.AllowedNetworks(uid)
+//	})
+//	http.HandleFunc("/admin", withFirebaseAuth(withOfficeIP(handler)))
+//
+// Both IPv4 and IPv6 networks are supported, single addresses being /32 and /128 networks, and IPv4-mapped IPv6
+// addresses match the IPv4 networks. The users without allowed networks are rejected, so a missing entry
+// doesn't lift the restriction; see `RequireIPAllowedIfSet` to let them through. Errors returned
+// by the getAllowed func are answered with 503 Service Unavailable.
+func RequireIPAllowed(getAllowed func(uid string) ([]net.IPNet, error)) func(http.HandlerFunc) http.HandlerFunc {
+	return requireIP(getAllowed, false)
+}
+
+// RequireIPAllowedIfSet is like `RequireIPAllowed`, but the users without allowed networks aren't restricted,
+// e.g. to only bind the accounts that opted in. Failing open, it's unfit for the checks that must hold
+// for every user of the route.
+func RequireIPAllowedIfSet(getAllowed func(uid string) ([]net.IPNet, error)) func(http.HandlerFunc) http.HandlerFunc {
+	return requireIP(getAllowed, true)
+}
+
+func requireIP(getAllowed func(uid string) ([]net.IPNet, error), ifSet bool) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		allowed, err := getAllowed(token.UID)
+		if err != nil {
+			return unavailable(fmt.Errorf("fauth: failed to get the allowed networks: %w", err))
+		}
+		if len(allowed) == 0 {
+			if ifSet {
+				return nil
+			}
+			return forbidden(fmt.Errorf("%w: no allowed networks", ErrIPNotAllowed))
+		}
+		ip, ok := ClientIP(r)
+		if !ok {
+			return forbidden(fmt.Errorf("%w: invalid client address", ErrIPNotAllowed))
+		}
+		for _, n := range allowed {
+			if n.Contains(ip) {
+				return nil
+			}
+		}
+		return forbidden(fmt.Errorf("%w: %s", ErrIPNotAllowed, ip))
+	})
+}
@@ -0,0 +1,123 @@
+package fauth_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func mustCIDR(t *testing.T, cidr string) net.IPNet {
+	t.Helper()
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *n
+}
+
+func TestClientIP(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.TrustedProxies = []net.IPNet{mustCIDR(t, "10.0.0.0/8"), mustCIDR(t, "fd00::/8")}
+	})
+	var (
+		ip net.IP
+		ok bool
+	)
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		ip, ok = fauth.ClientIP(r)
+	})
+	jwt := sign(map[string]any{"sub": "uid"})
+	tests := []struct {
+		remote string
+		xff    []string
+		ip     string
+	}{
+		{"203.0.113.1:1234", nil, "203.0.113.1"},
+		{"203.0.113.1:1234", []string{"198.51.100.1"}, "203.0.113.1"},
+		{"10.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"10.0.0.1:1234", []string{"1.1.1.1, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"10.0.0.1:1234", []string{"1.1.1.1", "198.51.100.1"}, "198.51.100.1"},
+		{"10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"[fd00::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"[2001:db8::2]:1234", nil, "2001:db8::2"},
+		{"10.0.0.1:1234", []string{"1.1.1.1, garbage"}, ""},
+		{"garbage", nil, ""},
+	}
+	for i, tt := range tests {
+		ip, ok = nil, false
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r.RemoteAddr = tt.remote
+		r.Header.Set("Authorization", "Bearer "+jwt)
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		h.ServeHTTP(w, r)
+		if tt.ip == "" {
+			if ok {
+				t.Fatalf("%d: unexpected ip %s", i, ip)
+			}
+			continue
+		}
+		if !ok || !ip.Equal(net.ParseIP(tt.ip)) {
+			t.Fatalf("%d: expected %s, got %s", i, tt.ip, ip)
+		}
+	}
+}
+
+func TestRequireIPAllowed(t *testing.T) {
+	getAllowed := func(uid string) ([]net.IPNet, error) {
+		switch uid {
+		case "admin":
+			return []net.IPNet{mustCIDR(t, "192.0.2.0/24"), mustCIDR(t, "2001:db8::/32")}, nil
+		case "broken":
+			return nil, errors.New("db is down")
+		}
+		return nil, nil
+	}
+	withFirebaseAuth, sign := offlineAuth(t)
+	h := withFirebaseAuth(fauth.RequireIPAllowed(getAllowed)(func(w http.ResponseWriter, r *http.Request) {}))
+	ifSet := withFirebaseAuth(fauth.RequireIPAllowedIfSet(getAllowed)(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(h http.HandlerFunc, uid, remote string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r.RemoteAddr = remote
+		r.Header.Set("Authorization", "Bearer "+sign(map[string]any{"sub": uid}))
+		h.ServeHTTP(w, r)
+		return w
+	}
+	tests := []struct {
+		uid    string
+		remote string
+		code   int
+	}{
+		{"admin", "192.0.2.10:1234", http.StatusOK},
+		{"admin", "[::ffff:192.0.2.10]:1234", http.StatusOK},
+		{"admin", "[2001:db8::1]:1234", http.StatusOK},
+		{"admin", "198.51.100.1:1234", http.StatusForbidden},
+		{"admin", "[2001:db9::1]:1234", http.StatusForbidden},
+		{"admin", "garbage", http.StatusForbidden},
+		{"user", "198.51.100.1:1234", http.StatusForbidden},
+		{"broken", "192.0.2.10:1234", http.StatusServiceUnavailable},
+	}
+	for i, tt := range tests {
+		w := serve(h, tt.uid, tt.remote)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if tt.code == http.StatusForbidden && w.Header().Get("X-Auth-Error") != "ip_not_allowed" {
+			t.Fatalf("%d: unexpected error code: %q", i, w.Header().Get("X-Auth-Error"))
+		}
+	}
+
+	if w := serve(ifSet, "user", "198.51.100.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("RequireIPAllowedIfSet should let the users without networks through, got: %d", w.Code)
+	}
+	if w := serve(ifSet, "admin", "198.51.100.1:1234"); w.Code != http.StatusForbidden {
+		t.Fatalf("RequireIPAllowedIfSet should restrict the users with networks, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package fauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/enfunc/fauth/internal/jwt"
+)
+
+// The public keys of a key set are cached for keysTTL. Unknown key IDs trigger a refetch,
+// at most once per keysMinRefetch, failed fetches included, to pick up the rotated keys.
+// Fetches are bounded by keysFetchTimeout, whichever request started them.
+const (
+	keysTTL          = time.Hour
+	keysMinRefetch   = time.Minute
+	keysFetchTimeout = 10 * time.Second
+)
+
+// keySet caches the public keys of a JSON Web Key Set, e.g. the ones of a Cloudflare Access team.
+type keySet struct {
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	// triedAt and err are the time and the error of the latest fetch, successful or not.
+	triedAt time.Time
+	err     error
+	// fetching is closed once the fetch in flight, if any, completes.
+	fetching chan struct{}
+}
+
+// key returns the public key with the ID, fetching the keys if they're missing or stale, according to
+// the `Engine.Now` clock. A single fetch is in flight at a time, the concurrent requests waiting for its outcome
+// rather than holding the lock. Fetch failures are answered with 503 Service Unavailable until the next attempt;
+// meanwhile, the stale keys are still served.
+func (s *keySet) key(ctx context.Context, kid string, fetch func(ctx context.Context) (map[string]*rsa.PublicKey, error)) (*rsa.PublicKey, error) {
+	for {
+		now := Now(ctx)
+		s.mu.Lock()
+		key, ok := s.keys[kid]
+		if ok && now.Sub(s.fetchedAt) < keysTTL {
+			s.mu.Unlock()
+			return key, nil
+		}
+		if now.Sub(s.triedAt) < keysMinRefetch {
+			err := s.err
+			s.mu.Unlock()
+			switch {
+			case ok:
+				return key, nil
+			case err != nil:
+				return nil, unavailable(err)
+			}
+			return nil, fmt.Errorf("%w: unknown key %s", ErrInvalidToken, kid)
+		}
+		if fetching := s.fetching; fetching != nil {
+			s.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, unavailable(ctx.Err())
+			}
+		}
+		fetching := make(chan struct{})
+		s.fetching = fetching
+		s.mu.Unlock()
+
+		// The fetch outlives the request starting it, as the other ones wait for its outcome.
+		fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), keysFetchTimeout)
+		keys, err := fetch(fctx)
+		cancel()
+		s.mu.Lock()
+		s.triedAt, s.err, s.fetching = now, err, nil
+		if err == nil {
+			s.keys, s.fetchedAt = keys, now
+		}
+		s.mu.Unlock()
+		close(fetching)
+	}
+}
+
+// fetchJWKS fetches the JSON Web Key Set at the URL, the name describing it in the errors.
+func fetchJWKS(ctx context.Context, client *http.Client, url, name string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fauth: failed to fetch the %s keys: %w", name, err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fauth: failed to fetch the %s keys: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fauth: failed to fetch the %s keys: %s", name, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fauth: failed to fetch the %s keys: %w", name, err)
+	}
+	keys, err := jwt.ParseJWKS(b)
+	if err != nil {
+		return nil, fmt.Errorf("fauth: failed to fetch the %s keys: %w", name, err)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("fauth: failed to fetch the " + name + " keys: empty key set")
+	}
+	return keys, nil
+}
@@ -0,0 +1,49 @@
+package fauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// refreshKeys keeps the public keys of the Auth client warm, verifying a dummy token every `Engine.KeyRefreshInterval`
+// until the context is done. The dummy token passes the cheap content checks, so the client consults its key cache,
+// refetching the keys when they've expired, and then fails the signature check, which is expected.
+func (a *Authenticator) refreshKeys(ctx context.Context) {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.engine.KeyRefreshInterval)
+	defer ticker.Stop()
+	for {
+		a.warmKeys(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Authenticator) warmKeys(ctx context.Context) {
+	_, err := a.current().client.VerifyIDToken(ctx, a.dummyToken())
+	if auth.IsCertificateFetchFailed(err) && a.engine.Logger != nil {
+		a.engine.Logger.WarnContext(ctx, "fauth: failed to refresh the public keys", "error", err)
+	}
+}
+
+// dummyToken returns an unsigned token for the `Engine.ProjectID`, valid enough to reach the signature check.
+func (a *Authenticator) dummyToken() string {
+	now := a.engine.Now()
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "kid": "fauth-key-refresh"})
+	payload, _ := json.Marshal(map[string]any{
+		"aud": a.engine.ProjectID,
+		"iss": "https://securetoken.google.com/" + a.engine.ProjectID,
+		"sub": "fauth-key-refresh",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(header) + "." + enc.EncodeToString(payload) + "." + enc.EncodeToString([]byte("unsigned"))
+}
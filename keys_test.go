@@ -0,0 +1,36 @@
+package fauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestKeyRefreshClose(t *testing.T) {
+	// The emulator skips the signature check, keeping the refresh from reaching Google.
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", "localhost:1")
+	a, err := fauth.NewAuthenticator(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.ProjectID = fauthtest.ProjectID
+		e.KeyRefreshInterval = time.Millisecond
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = a.Close()
+		_ = a.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close should stop the key refresh")
+	}
+}
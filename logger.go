@@ -0,0 +1,77 @@
+package fauth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// tokenPrefixLen is the length of the token prefix logged on verification failures, short enough not to leak the token.
+const tokenPrefixLen = 8
+
+const loggerContextKey contextKey = "logger"
+
+// WithLogger returns a copy of the `context.Context` with the given logger.
+// To retrieve it, use the `Logger` func.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// Logger returns the logger stored in the context, falling back to `slog.Default()`.
+// When `Engine.Logger` is set, the logger of a verified request carries the `uid` and `provider` attributes,
+// along with the `request_id` when `Engine.RequestIDHeader` is set.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// withLogger stores the `Engine.Logger`, annotated with the identity of the verified token, in the request context.
+func (e *Engine) withLogger(r *http.Request, data any) *http.Request {
+	if e.Logger == nil {
+		return r
+	}
+	logger := e.Logger
+	if id, ok := RequestID(r.Context()); ok {
+		logger = logger.With(slog.String("request_id", id))
+	}
+	if token, ok := tokenOf(data); ok && token != nil {
+		logger = logger.With(slog.String("uid", token.UID), slog.String("provider", token.Firebase.SignInProvider))
+	}
+	return r.WithContext(WithLogger(r.Context(), logger))
+}
+
+// logFailure logs the verification failure to the `Engine.Logger`, if set, along with the request path,
+// the redacted token and the request ID, if any. 5xx failures are logged as errors, the other ones as warnings.
+func (e *Engine) logFailure(r *http.Request, err error) {
+	if e.Logger == nil || err == nil {
+		return
+	}
+	code := StatusCode(err)
+	level := slog.LevelWarn
+	if code >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	attrs := []slog.Attr{
+		slog.String("path", r.URL.Path),
+		slog.Int("status", code),
+		slog.String("code", ErrorCode(err)),
+		slog.String("error", err.Error()),
+	}
+	if jwt, err := ExtractToken(r); err == nil {
+		attrs = append(attrs, slog.String("token", redactToken(jwt)))
+	}
+	if id, ok := RequestID(r.Context()); ok {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	e.Logger.LogAttrs(r.Context(), level, "fauth: verification failed", attrs...)
+}
+
+// redactToken returns the prefix of the token, never the full token.
+func redactToken(jwt string) string {
+	if len(jwt) <= 2*tokenPrefixLen {
+		return "[redacted]"
+	}
+	return jwt[:tokenPrefixLen] + "..."
+}
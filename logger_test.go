@@ -0,0 +1,76 @@
+package fauth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestLogger(t *testing.T) {
+	if fauth.Logger(context.Background()) != slog.Default() {
+		t.Fatal("the default logger should be returned")
+	}
+
+	var buf bytes.Buffer
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		fauth.Logger(r.Context()).Info("hello")
+	})
+	serveBearer(h, sign(map[string]any{"sub": "uid", "firebase": map[string]any{"sign_in_provider": "password"}}))
+
+	if out := buf.String(); !strings.Contains(out, "uid=uid") || !strings.Contains(out, "provider=password") {
+		t.Fatalf("the logger should carry the auth fields: %s", out)
+	}
+}
+
+func TestLogFailures(t *testing.T) {
+	var buf bytes.Buffer
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	jwt := sign(map[string]any{"sub": "uid", "exp": time.Now().Add(-time.Minute).Unix()})
+	serveBearer(h, jwt)
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "WARN" || entry["path"] != "" || entry["code"] != "expired" || entry["status"] != 401.0 {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+	if entry["token"] != jwt[:8]+"..." || strings.Contains(buf.String(), jwt) {
+		t.Fatalf("the token should be redacted: %v", entry["token"])
+	}
+
+	buf.Reset()
+	serveBearer(h, "short")
+	if out := buf.String(); !strings.Contains(out, `"token":"[redacted]"`) {
+		t.Fatalf("short tokens should be redacted: %s", out)
+	}
+
+	buf.Reset()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Token "+jwt)
+	h.ServeHTTP(w, r)
+	if out := buf.String(); !strings.Contains(out, `"code":"malformed"`) || strings.Contains(out, jwt) {
+		t.Fatalf("the malformed headers shouldn't be logged: %s", out)
+	}
+
+	buf.Reset()
+	serveBearer(h, sign(map[string]any{"sub": "uid"}))
+	if buf.Len() != 0 {
+		t.Fatalf("successes shouldn't be logged: %s", buf.String())
+	}
+}
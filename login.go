@@ -0,0 +1,103 @@
+package fauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultSessionCookieName = "session"
+	defaultSessionLifetime   = 5 * 24 * time.Hour
+)
+
+// LoginHandler returns a handler for login endpoints, exchanging the posted ID token for a session cookie in one step.
+// It verifies the ID token like `Wrap`, running the `Engine` hooks and checks from `PreVerify` to `OnData`,
+// mints a session cookie named `Engine.SessionCookieName`, lasting `Engine.SessionLifetime`, sets it on the response
+// and answers 200 OK with the user as JSON, e.g.:
+//
+//	{"uid":"42","email":"user@example.com","email_verified":true,"name":"User","picture":"https://..."}
+//
+// Failures are passed to the `Engine.OnErr` func. The cookie is `HttpOnly`, `Secure` and `SameSite=Lax`;
+// as with any cookie-based session, make sure the endpoint is protected against CSRF.
+//
+// The Firebase Admin SDK only mints the session cookies of the project users, so the `Engine.TenantID` is rejected.
+func LoginHandler(ctx context.Context, opts ...Option) (http.HandlerFunc, error) {
+	a, err := NewAuthenticator(ctx, append([]Option{WithoutClose("LoginHandler")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if a.engine.TenantID != nil {
+		return nil, errors.New("fauth: LoginHandler doesn't support the TenantID, the session cookies can't be minted for tenants")
+	}
+	return a.login, nil
+}
+
+// loginUser is the user the login handler responds with.
+type loginUser struct {
+	UID           string `json:"uid"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+}
+
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request) {
+	s := a.current()
+	engine, app, cli := s.engine, s.app, s.client
+	r, data, err := a.verify(s, w, r)
+	defer removeForm(r)
+	if err == nil && data == nil {
+		err = errNoAuthToken
+	}
+	engine.stats.record(err)
+	engine.logFailure(r, err)
+	if err != nil {
+		engine.OnErr(w, r, app, cli, err)
+		return
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		engine.OnErr(w, r, app, cli, errNoAuthToken)
+		return
+	}
+	jwt, err := rawToken(r, data)
+	if err != nil {
+		engine.OnErr(w, r, app, cli, err)
+		return
+	}
+	cookie, err := cli.SessionCookie(r.Context(), jwt, engine.SessionLifetime)
+	if err != nil {
+		engine.OnErr(w, r, app, cli, unavailable(fmt.Errorf("fauth: failed to create the session cookie: %w", err)))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     engine.SessionCookieName,
+		Value:    cookie,
+		Path:     "/",
+		MaxAge:   int(engine.SessionLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	claims := Claims(token.Claims)
+	user := loginUser{UID: token.UID}
+	user.Email, _ = claims.String("email")
+	user.EmailVerified, _ = claims.Bool("email_verified")
+	user.Name, _ = claims.String("name")
+	user.Picture, _ = claims.String("picture")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(user)
+}
+
+// rawToken returns the encoded token the auth data was verified from.
+func rawToken(r *http.Request, data any) (string, error) {
+	if res, ok := data.(*Result); ok && res != nil && res.JWT != "" {
+		return res.JWT, nil
+	}
+	return ExtractToken(r)
+}
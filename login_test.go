@@ -0,0 +1,79 @@
+package fauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestLoginHandler(t *testing.T) {
+	fakeEmulator(t, map[string]string{"createSessionCookie": `{"sessionCookie":"cookie"}`})
+
+	_, sign := offlineAuth(t)
+	login, err := fauth.LoginHandler(context.Background(), fauthtest.NewVerifier(&testKey.PublicKey).Option(), func(e *fauth.Engine) {
+		e.SessionCookieName = "__session"
+		e.SessionLifetime = time.Hour
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveBearer(login, sign(map[string]any{"sub": "uid", "email": "user@example.com", "email_verified": true}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "__session" || cookies[0].Value != "cookie" ||
+		cookies[0].MaxAge != 3600 || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("invalid cookies: %v", cookies)
+	}
+	var user map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user["uid"] != "uid" || user["email"] != "user@example.com" || user["email_verified"] != true {
+		t.Fatalf("invalid user: %v", user)
+	}
+
+	if w := serveBearer(login, "invalid"); w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("invalid tokens shouldn't log in, got: %d", w.Code)
+	}
+}
+
+func TestLoginHandlerChecks(t *testing.T) {
+	fakeEmulator(t, map[string]string{"createSessionCookie": `{"sessionCookie":"cookie"}`})
+
+	_, sign := offlineAuth(t)
+	login, err := fauth.LoginHandler(context.Background(), fauthtest.NewVerifier(&testKey.PublicKey).Option(), func(e *fauth.Engine) {
+		e.ProjectID = "project"
+		e.MaxTokenLifetime = time.Hour
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		claims map[string]any
+		code   int
+	}{
+		{map[string]any{"sub": "uid", "aud": "project", "iss": "https://securetoken.google.com/project"}, http.StatusOK},
+		{map[string]any{"sub": "uid", "aud": "other", "iss": "https://securetoken.google.com/other"}, http.StatusForbidden},
+		{map[string]any{"sub": "uid", "aud": "project", "iss": "https://securetoken.google.com/project",
+			"iat": now.Unix(), "exp": now.Add(24 * time.Hour).Unix()}, http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		w := serveBearer(login, sign(tt.claims))
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if cookies := w.Result().Cookies(); (tt.code == http.StatusOK) != (len(cookies) == 1) {
+			t.Fatalf("%d: unexpected cookies: %v", i, cookies)
+		}
+	}
+}
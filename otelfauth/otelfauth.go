@@ -0,0 +1,64 @@
+// Package otelfauth traces the fauth verifications with OpenTelemetry.
+//
+// It's a separate module, so the fauth package doesn't depend on OpenTelemetry.
+package otelfauth
+
+import (
+	"context"
+
+	"github.com/enfunc/fauth"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/enfunc/fauth/otelfauth"
+
+// SpanName is the name of the verification spans.
+const SpanName = "fauth.VerifyIDToken"
+
+// Attributes of the verification spans.
+const (
+	// CheckRevokedKey reports whether the token was checked for revocation.
+	CheckRevokedKey = attribute.Key("fauth.check_revoked")
+	// CacheKey is the outcome of the token cache lookup, `hit`, `miss` or `stale_hit`, if any.
+	CacheKey = attribute.Key("fauth.cache")
+	// OutcomeKey is the outcome of the verification, `ok` or the code of the error, see `fauth.VerifyTrace.Outcome`.
+	OutcomeKey = attribute.Key("fauth.outcome")
+)
+
+// Tracing returns an Option making each verification a child span of the span of the request context,
+// using the tracer provider, or the global one if nil, which is a no-op until one is configured:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, otelfauth.Tracing(nil))
+//
+// Failed verifications are recorded as span errors.
+func Tracing(tp trace.TracerProvider) fauth.Option {
+	return func(e *fauth.Engine) {
+		e.TraceVerify = TraceVerify(tp)
+	}
+}
+
+// TraceVerify returns an `Engine.TraceVerify` func, see `Tracing`.
+func TraceVerify(tp trace.TracerProvider) func(ctx context.Context) (context.Context, func(t *fauth.VerifyTrace)) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer(ScopeName)
+	return func(ctx context.Context) (context.Context, func(t *fauth.VerifyTrace)) {
+		ctx, span := tracer.Start(ctx, SpanName, trace.WithSpanKind(trace.SpanKindInternal))
+		return ctx, func(t *fauth.VerifyTrace) {
+			span.SetAttributes(CheckRevokedKey.Bool(t.CheckRevoked), OutcomeKey.String(t.Outcome()))
+			if t.Cache != "" {
+				span.SetAttributes(CacheKey.String(t.Cache))
+			}
+			if t.Err != nil {
+				span.RecordError(t.Err)
+				span.SetStatus(codes.Error, t.Outcome())
+			}
+			span.End()
+		}
+	}
+}
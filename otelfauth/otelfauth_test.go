@@ -0,0 +1,62 @@
+package otelfauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+	"github.com/enfunc/fauth/otelfauth"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	withFirebaseAuth, err := fauth.Auth(context.Background(), fauthtest.NewVerifier(&key.PublicKey).Option(), otelfauth.Tracing(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	parent, span := tp.Tracer("test").Start(context.Background(), "request")
+	jwt, err := fauthtest.NewSignedToken(map[string]any{"sub": "uid"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{jwt, "invalid"} {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil).WithContext(parent)
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for i, outcome := range []string{"ok", "invalid_token"} {
+		s := spans[i]
+		if s.Name() != otelfauth.SpanName || s.Parent().SpanID() != span.SpanContext().SpanID() {
+			t.Fatalf("%d: unexpected span: %s", i, s.Name())
+		}
+		attrs := attribute.NewSet(s.Attributes()...)
+		if v, _ := attrs.Value(otelfauth.OutcomeKey); v.AsString() != outcome {
+			t.Fatalf("%d: expected outcome %s, got %s", i, outcome, v.AsString())
+		}
+		if failed := s.Status().Code == codes.Error; failed != (outcome != "ok") {
+			t.Fatalf("%d: unexpected status: %v", i, s.Status())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package fauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+const permissionsContextKey contextKey = "permissions"
+
+// WithPermissions returns a copy of the `context.Context` with the given permissions.
+// To retrieve them, use the `Permissions` func.
+func WithPermissions(ctx context.Context, permissions []string) context.Context {
+	return context.WithValue(ctx, permissionsContextKey, permissions)
+}
+
+// Permissions returns the permissions of the user resolved by the `Engine.Permissions` func.
+func Permissions(ctx context.Context) ([]string, bool) {
+	permissions, ok := ctx.Value(permissionsContextKey).([]string)
+	return permissions, ok
+}
+
+// RequirePermission returns a middleware func rejecting the request with 403 Forbidden, and `ErrMissingPermission`,
+// unless the user has the given permission, as resolved by the `Engine.Permissions` func.
+//
+//	withRefunds := fauth.RequirePermission("billing.refund")
+//	http.HandleFunc("/refunds", withFirebaseAuth(withRefunds(handler)))
+func RequirePermission(p string) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		permissions, _ := Permissions(r.Context())
+		for _, permission := range permissions {
+			if permission == p {
+				return nil
+			}
+		}
+		return forbidden(fmt.Errorf("%w: %s", ErrMissingPermission, p))
+	})
+}
+
+// resolvePermissions stores the permissions resolved by the `Engine.Permissions` func in the request context.
+func (e *Engine) resolvePermissions(r *http.Request, data any) (*http.Request, error) {
+	token, ok := tokenOf(data)
+	if !ok || token == nil || e.Permissions == nil {
+		return r, nil
+	}
+	permissions, err := e.Permissions(r.Context(), token)
+	if err != nil {
+		return r, unavailable(fmt.Errorf("fauth: failed to resolve the permissions: %w", err))
+	}
+	return r.WithContext(WithPermissions(r.Context(), permissions)), nil
+}
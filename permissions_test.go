@@ -0,0 +1,44 @@
+package fauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestPermissions(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.Permissions = func(ctx context.Context, token *auth.Token) ([]string, error) {
+			switch token.UID {
+			case "admin":
+				return []string{"billing.read", "billing.refund"}, nil
+			case "broken":
+				return nil, errors.New("policy store is down")
+			}
+			return []string{"billing.read"}, nil
+		}
+	})
+	var permissions []string
+	h := withFirebaseAuth(fauth.RequirePermission("billing.refund")(func(w http.ResponseWriter, r *http.Request) {
+		permissions, _ = fauth.Permissions(r.Context())
+	}))
+	for uid, code := range map[string]int{
+		"admin":  http.StatusOK,
+		"user":   http.StatusForbidden,
+		"broken": http.StatusServiceUnavailable,
+	} {
+		if w := serveBearer(h, sign(map[string]any{"sub": uid})); w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", uid, code, w.Code)
+		}
+	}
+	if len(permissions) != 2 {
+		t.Fatalf("invalid permissions: %v", permissions)
+	}
+	if _, ok := fauth.Permissions(context.Background()); ok {
+		t.Fatal("permissions shouldn't be present")
+	}
+}
@@ -0,0 +1,19 @@
+package fauth
+
+import (
+	"context"
+)
+
+const profileContextKey contextKey = "profile"
+
+// WithProfile returns a copy of the `context.Context` with the given profile.
+// To retrieve it, use the `Profile` func.
+func WithProfile(ctx context.Context, profile any) context.Context {
+	return context.WithValue(ctx, profileContextKey, profile)
+}
+
+// Profile returns the profile loaded by the `Engine.LoadProfile` func.
+func Profile(ctx context.Context) (any, bool) {
+	profile := ctx.Value(profileContextKey)
+	return profile, profile != nil
+}
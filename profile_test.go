@@ -0,0 +1,52 @@
+package fauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestLoadProfile(t *testing.T) {
+	profiles := map[string]string{"uid": "Jane"}
+	loadProfile := func(ctx context.Context, uid string) (any, error) {
+		if p, ok := profiles[uid]; ok {
+			return p, nil
+		}
+		return nil, errors.New("profile not found")
+	}
+
+	tests := []struct {
+		uid     string
+		fatal   bool
+		code    int
+		profile any
+	}{
+		{"uid", false, http.StatusOK, "Jane"},
+		{"uid", true, http.StatusOK, "Jane"},
+		{"unknown", false, http.StatusOK, nil},
+		{"unknown", true, http.StatusServiceUnavailable, nil},
+	}
+	for _, tt := range tests {
+		withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+			e.LoadProfile = loadProfile
+			e.OnDataFatal = tt.fatal
+		})
+		var profile any
+		h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+			profile, _ = fauth.Profile(r.Context())
+			if _, ok := fauth.AuthToken(r.Context()); !ok {
+				t.Error("the token should be stored alongside the profile")
+			}
+		})
+		w := serveBearer(h, sign(map[string]any{"sub": tt.uid}))
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected %d, got %d", tt.uid, tt.code, w.Code)
+		}
+		if profile != tt.profile {
+			t.Fatalf("%s: invalid profile: %v", tt.uid, profile)
+		}
+	}
+}
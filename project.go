@@ -0,0 +1,30 @@
+package fauth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The issuers of the ID tokens and the session cookies, followed by the project ID.
+var projectIssuers = []string{"https://securetoken.google.com/", "https://session.firebase.google.com/"}
+
+// checkProject makes sure the verified token was issued for the `Engine.ProjectID`, if set, catching cross-project
+// token confusion, e.g. due to a misconfigured Firebase app, even when the verifier accepted the token.
+func (e *Engine) checkProject(data any) error {
+	if e.ProjectID == "" {
+		return nil
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return nil
+	}
+	if token.Audience != e.ProjectID {
+		return forbidden(fmt.Errorf("%w: expected audience %s, got %s", ErrWrongProject, e.ProjectID, token.Audience))
+	}
+	for _, prefix := range projectIssuers {
+		if project, ok := strings.CutPrefix(token.Issuer, prefix); ok && project == e.ProjectID {
+			return nil
+		}
+	}
+	return forbidden(fmt.Errorf("%w: expected issuer of %s, got %s", ErrWrongProject, e.ProjectID, token.Issuer))
+}
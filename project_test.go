@@ -0,0 +1,35 @@
+package fauth_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestProjectID(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.ProjectID = "app"
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		aud, iss string
+		code     int
+	}{
+		{"app", "https://securetoken.google.com/app", http.StatusOK},
+		{"app", "https://session.firebase.google.com/app", http.StatusOK},
+		{"sibling", "https://securetoken.google.com/sibling", http.StatusForbidden},
+		{"app", "https://securetoken.google.com/sibling", http.StatusForbidden},
+		{"app", "https://evil.example.com/app", http.StatusForbidden},
+		{"", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := serveBearer(h, sign(map[string]any{"sub": "uid", "aud": tt.aud, "iss": tt.iss}))
+		if w.Code != tt.code {
+			t.Fatalf("%s, %s: expected %d, got %d", tt.aud, tt.iss, tt.code, w.Code)
+		}
+		if tt.code == http.StatusForbidden && w.Header().Get("X-Auth-Error") != "wrong_project" {
+			t.Fatalf("invalid error code: %s", w.Header().Get("X-Auth-Error"))
+		}
+	}
+}
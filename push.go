@@ -0,0 +1,42 @@
+package fauth
+
+import (
+	"errors"
+	"net/http"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// PushAuthorized pushes the targets the allow func authorizes for the verified token of the request,
+// skipping the others, so HTTP/2 server pushes respect the permissions of the user:
+//
+//	withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+//		_ = fauth.PushAuthorized(w, r, func(token *auth.Token, target string) bool {
+//			return target != "/admin.js" || token.Claims["admin"] == true
+//		}, "/app.js", "/admin.js")
+//		...
+//	})
+//
+// It must run in a handler wrapped by `Auth`. It returns `http.ErrNotSupported` when the connection doesn't support
+// server push, which is the case of HTTP/1.x and of most browsers nowadays, since they've deprecated HTTP/2 push:
+// treat it as an optimization that may not happen. Failed pushes are returned joined, after trying all the targets.
+func PushAuthorized(w http.ResponseWriter, r *http.Request, allow func(token *auth.Token, target string) bool, targets ...string) error {
+	token, ok := AuthToken(r.Context())
+	if !ok || token == nil {
+		return errNoAuthToken
+	}
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	var errs []error
+	for _, target := range targets {
+		if !allow(token, target) {
+			continue
+		}
+		if err := pusher.Push(target, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
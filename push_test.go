@@ -0,0 +1,52 @@
+package fauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+type pusher struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pusher) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestPushAuthorized(t *testing.T) {
+	allow := func(token *auth.Token, target string) bool {
+		return target != "/admin.js" || token.Claims["admin"] == true
+	}
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	if err := fauth.PushAuthorized(&pusher{}, r, allow, "/app.js"); !errors.Is(err, fauth.ErrNoToken) {
+		t.Fatalf("expected ErrNoToken, got: %v", err)
+	}
+
+	for admin, pushed := range map[bool][]string{
+		true:  {"/app.js", "/admin.js"},
+		false: {"/app.js"},
+	} {
+		token := &auth.Token{Claims: map[string]any{"admin": admin}}
+		r := r.WithContext(fauth.WithAuthData(context.Background(), token))
+		w := &pusher{ResponseRecorder: httptest.NewRecorder()}
+		if err := fauth.PushAuthorized(w, r, allow, "/app.js", "/admin.js"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(w.pushed, pushed) {
+			t.Fatalf("admin %t: expected %v, got %v", admin, pushed, w.pushed)
+		}
+		err := fauth.PushAuthorized(httptest.NewRecorder(), r, allow, "/app.js")
+		if !errors.Is(err, http.ErrNotSupported) {
+			t.Fatalf("expected ErrNotSupported, got: %v", err)
+		}
+	}
+}
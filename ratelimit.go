@@ -0,0 +1,112 @@
+package fauth
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// RateLimitBy returns a middleware func rate limiting the requests by the key the keyFn derives from the verified
+// token, e.g. an `org_id` claim for org-level quotas. Each key gets its own token bucket, refilled at limit requests
+// per second and holding up to burst requests. Requests exceeding it are rejected with 429 Too Many Requests,
+// `ErrRateLimited` and a `Retry-After` header. Tokens for which the keyFn returns an empty key are limited by UID.
+//
+//	withOrgQuota := fauth.RateLimitBy(func(token *auth.Token) string {
+//		org, _ := token.Claims["org_id"].(string)
+//		return org
+//	}, 10, 20)
+//	http.HandleFunc("/api", withFirebaseAuth(withOrgQuota(handler)))
+//
+// The buckets are kept in memory, so each instance of a horizontally scaled service enforces its own limits.
+// It panics if limit isn't positive or burst is less than 1.
+func RateLimitBy(keyFn func(token *auth.Token) string, limit float64, burst int) func(http.HandlerFunc) http.HandlerFunc {
+	if limit <= 0 || math.IsNaN(limit) || burst < 1 {
+		panic(fmt.Sprintf("fauth: invalid rate limit %v with burst %d", limit, burst))
+	}
+	l := &rateLimiter{limit: limit, burst: float64(burst), buckets: map[string]*bucket{}, sweepAt: minSweep}
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := AuthToken(r.Context())
+			if !ok || token == nil {
+				fail(w, r, errNoAuthToken)
+				return
+			}
+			key := keyFn(token)
+			if key == "" {
+				key = token.UID
+			}
+			if wait, ok := l.allow(key, Now(r.Context())); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				fail(w, r, withStatus(http.StatusTooManyRequests, fmt.Errorf("%w: %s", ErrRateLimited, key)))
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+	}
+}
+
+// RateLimitByUID is like `RateLimitBy`, but limits the requests per user.
+func RateLimitByUID(limit float64, burst int) func(http.HandlerFunc) http.HandlerFunc {
+	return RateLimitBy(func(token *auth.Token) string { return token.UID }, limit, burst)
+}
+
+// minSweep is the number of buckets above which the full ones are swept.
+const minSweep = 1024
+
+type rateLimiter struct {
+	limit   float64
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweepAt int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket of the key, or reports how long to wait for the next one.
+func (l *rateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.sweepAt {
+			l.sweep(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.limit * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func (l *rateLimiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.limit)
+		b.last = now
+	}
+}
+
+// sweep drops the full buckets, which behave like new ones, keeping the memory bounded by the active keys.
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now); b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweepAt = 2 * len(l.buckets)
+	if l.sweepAt < minSweep {
+		l.sweepAt = minSweep
+	}
+}
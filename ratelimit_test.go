@@ -0,0 +1,77 @@
+package fauth_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestRateLimitBy(t *testing.T) {
+	now := time.Now()
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.Now = func() time.Time { return now }
+	})
+	withOrgQuota := fauth.RateLimitBy(func(token *auth.Token) string {
+		org, _ := token.Claims["org_id"].(string)
+		return org
+	}, 1, 2)
+	h := withFirebaseAuth(withOrgQuota(func(w http.ResponseWriter, r *http.Request) {}))
+
+	alice := sign(map[string]any{"sub": "alice", "org_id": "acme"})
+	bob := sign(map[string]any{"sub": "bob", "org_id": "acme"})
+	carol := sign(map[string]any{"sub": "carol"})
+	tests := []struct {
+		jwt     string
+		advance time.Duration
+		code    int
+	}{
+		{alice, 0, http.StatusOK},
+		{bob, 0, http.StatusOK},
+		{alice, 0, http.StatusTooManyRequests},
+		{carol, 0, http.StatusOK},
+		{carol, 0, http.StatusOK},
+		{carol, 0, http.StatusTooManyRequests},
+		{bob, time.Second, http.StatusOK},
+		{alice, 0, http.StatusTooManyRequests},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		w := serveBearer(h, tt.jwt)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if tt.code == http.StatusTooManyRequests {
+			if e := w.Header().Get("X-Auth-Error"); e != "rate_limited" {
+				t.Fatalf("%d: invalid error: %q", i, e)
+			}
+			if ra := w.Header().Get("Retry-After"); ra != "1" {
+				t.Fatalf("%d: invalid Retry-After: %q", i, ra)
+			}
+		}
+	}
+}
+
+func TestRateLimitByUID(t *testing.T) {
+	mw := fauth.RateLimitByUID(1, 1)
+	for i, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if c := serveWithToken(mw, &auth.Token{UID: "uid"}); c != code {
+			t.Fatalf("%d: expected %d, got %d", i, code, c)
+		}
+	}
+	if c := serveWithToken(mw, &auth.Token{UID: "other"}); c != http.StatusOK {
+		t.Fatalf("expected 200, got %d", c)
+	}
+	if c := serveWithToken(mw, nil); c != http.StatusUnauthorized {
+		t.Fatalf("missing token: expected 401, got %d", c)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	fauth.RateLimitByUID(0, 1)
+}
@@ -0,0 +1,74 @@
+package fauth
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// RedirectErrorHandler returns an `Engine.OnErr` func redirecting the unauthenticated browsers to the login page,
+// for server-rendered apps:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnErr = fauth.RedirectErrorHandler("/login")
+//	})
+//
+// The requests accepting HTML, see `AcceptsHTML`, failing with a 401 Unauthorized are redirected with 302 Found,
+// the original path and query being preserved in the `next` query parameter, e.g. `/login?next=%2Fcart`.
+// The other ones, e.g. of API clients, and the other failures, e.g. 403 Forbidden, are answered with a JSON payload,
+// see `WriteJSONError`. It panics if the loginURL can't be parsed.
+func RedirectErrorHandler(loginURL string) func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+	return RedirectErrorHandlerFor(loginURL, AcceptsHTML)
+}
+
+// RedirectErrorHandlerFor is like `RedirectErrorHandler`, but redirects the requests the wantsHTML func reports, e.g.
+// to redirect all the GET requests outside of `/api/`.
+func RedirectErrorHandlerFor(
+	loginURL string,
+	wantsHTML func(r *http.Request) bool,
+) func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+	login, err := url.Parse(loginURL)
+	if err != nil {
+		panic(fmt.Sprintf("fauth: invalid login url %q: %v", loginURL, err))
+	}
+	return func(w http.ResponseWriter, r *http.Request, app *firebase.App, client *auth.Client, err error) {
+		if StatusCode(err) != http.StatusUnauthorized || !wantsHTML(r) {
+			writeError(w, r, err, true)
+			return
+		}
+		if c := ErrorCode(err); c != "" {
+			w.Header().Set("X-Auth-Error", c)
+		}
+		u := *login
+		q := u.Query()
+		q.Set("next", r.URL.RequestURI())
+		u.RawQuery = q.Encode()
+		http.Redirect(w, r, u.String(), http.StatusFound)
+	}
+}
+
+// AcceptsHTML reports whether the `Accept` header of the request lists `text/html` or `application/xhtml+xml`,
+// as the browsers do when navigating, with a non-zero quality.
+func AcceptsHTML(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+				continue
+			}
+			if q, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(q, 64); err != nil || f <= 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
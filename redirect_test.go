@@ -0,0 +1,86 @@
+package fauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestRedirectErrorHandler(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.OnErr = fauth.RedirectErrorHandler("/login?lang=en")
+	})
+	h := withFirebaseAuth(fauth.RequireClaimPresent("tenant")(func(w http.ResponseWriter, r *http.Request) {}))
+	const browser = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	tests := []struct {
+		accept   string
+		jwt      string
+		code     int
+		location string
+		body     string
+	}{
+		{browser, "", http.StatusFound, "/login?lang=en&next=%2Fcart%3Fid%3D1", ""},
+		{browser, "garbage", http.StatusFound, "/login?lang=en&next=%2Fcart%3Fid%3D1", ""},
+		{"application/json", "", http.StatusUnauthorized, "",
+			`{"error":"unauthorized","code":"no_token","message":"missing token"}`},
+		{"", "", http.StatusUnauthorized, "",
+			`{"error":"unauthorized","code":"no_token","message":"missing token"}`},
+		{browser, sign(map[string]any{"sub": "uid"}), http.StatusForbidden, "",
+			`{"error":"forbidden","code":"missing_claim","message":"missing claim"}`},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/cart?id=1", nil)
+		r.Header.Set("Accept", tt.accept)
+		if tt.jwt != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.jwt)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if l := w.Header().Get("Location"); l != tt.location {
+			t.Fatalf("%d: expected %q, got %q", i, tt.location, l)
+		}
+		if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Fatalf("%d: expected %s, got %s", i, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestRedirectErrorHandlerFor(t *testing.T) {
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.OnErr = fauth.RedirectErrorHandlerFor("https://auth.example.com/login", func(r *http.Request) bool {
+			return !strings.HasPrefix(r.URL.Path, "/api/")
+		})
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	for path, code := range map[string]int{"/cart": http.StatusFound, "/api/cart": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://www.example.com"+path, nil))
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+}
+
+func TestAcceptsHTML(t *testing.T) {
+	for accept, ok := range map[string]bool{
+		"text/html":                   true,
+		"application/json, TEXT/HTML": true,
+		"application/xhtml+xml;q=0.9": true,
+		"text/html;q=0":               false,
+		"application/json":            false,
+		"*/*":                         false,
+		"":                            false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r.Header.Set("Accept", accept)
+		if fauth.AcceptsHTML(r) != ok {
+			t.Fatalf("%q: expected %v", accept, ok)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package fauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// ReplayGuard records the keys that may only be used once, e.g. the IDs of single-use download tokens.
+//
+// Use the in-memory `MemoryReplayGuard` for single instances. Horizontally scaled services need a guard
+// backed by a shared store, recording the key atomically along with its expiry, e.g. using Redis:
+//
+//	func (g *RedisGuard) Use(ctx context.Context, key string, expires time.Time) (bool, error) {
+//		return g.client.SetNX(ctx, "fauth:used:"+key, 1, time.Until(expires)).Result()
+//	}
+type ReplayGuard interface {
+	// Use records the key as used until it expires, reporting whether it was unused until now.
+	// Concurrent calls with the same key must report only one of them as unused.
+	Use(ctx context.Context, key string, expires time.Time) (bool, error)
+}
+
+// MemoryReplayGuard is a `ReplayGuard` keeping the used keys in memory until they expire.
+// Each instance of a horizontally scaled service has its own, so use a shared store across instances.
+type MemoryReplayGuard struct {
+	mu      sync.Mutex
+	used    map[string]time.Time
+	sweepAt int
+}
+
+// NewMemoryReplayGuard returns an empty MemoryReplayGuard.
+func NewMemoryReplayGuard() *MemoryReplayGuard {
+	return &MemoryReplayGuard{used: map[string]time.Time{}, sweepAt: minSweep}
+}
+
+// Use records the key as used until it expires, reporting whether it was unused until now.
+func (g *MemoryReplayGuard) Use(ctx context.Context, key string, expires time.Time) (bool, error) {
+	now := Now(ctx)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if exp, ok := g.used[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	g.used[key] = expires
+	if len(g.used) >= g.sweepAt {
+		for k, exp := range g.used {
+			if !now.Before(exp) {
+				delete(g.used, k)
+			}
+		}
+		g.sweepAt = 2 * len(g.used)
+		if g.sweepAt < minSweep {
+			g.sweepAt = minSweep
+		}
+	}
+	return true, nil
+}
+
+// RequireOneTimeUse returns a middleware func letting each value of the claim through only once, e.g. the
+// `download_id` claim of the tokens issued for single downloads. The value is recorded by the guard until the token
+// expires, the reuses being rejected with 410 Gone and `ErrTokenReused`:
+//
+//	withSingleDownload := fauth.RequireOneTimeUse("download_id", fauth.NewMemoryReplayGuard())
+//	http.HandleFunc("/download", withFirebaseAuth(withSingleDownload(handler)))
+//
+// Tokens without the claim, which must be a string, are rejected with 403 Forbidden and `ErrMissingClaim`,
+// and the errors of the guard are answered with 503 Service Unavailable. The keys are prefixed with the claim,
+// so guards can be shared by the middleware funcs of different claims.
+func RequireOneTimeUse(claim string, guard ReplayGuard) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		id, ok := claims.String(claim)
+		if !ok || id == "" {
+			return forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, claim))
+		}
+		unused, err := guard.Use(r.Context(), claim+":"+id, time.Unix(token.Expires, 0))
+		if err != nil {
+			return unavailable(fmt.Errorf("fauth: failed to check the %s claim: %w", claim, err))
+		}
+		if !unused {
+			return withStatus(http.StatusGone, fmt.Errorf("%w: %s %s", ErrTokenReused, claim, id))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package fauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+)
+
+type brokenGuard struct{}
+
+func (brokenGuard) Use(ctx context.Context, key string, expires time.Time) (bool, error) {
+	return false, errors.New("store is down")
+}
+
+func TestRequireOneTimeUse(t *testing.T) {
+	guard := fauth.NewMemoryReplayGuard()
+	withFirebaseAuth, sign := offlineAuth(t)
+	h := withFirebaseAuth(fauth.RequireOneTimeUse("download_id", guard)(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		claims map[string]any
+		code   int
+		err    string
+	}{
+		{map[string]any{"download_id": "d1"}, http.StatusOK, ""},
+		{map[string]any{"download_id": "d1"}, http.StatusGone, "token_reused"},
+		{map[string]any{"download_id": "d2"}, http.StatusOK, ""},
+		{map[string]any{}, http.StatusForbidden, "missing_claim"},
+		{map[string]any{"download_id": 1.0}, http.StatusForbidden, "missing_claim"},
+	}
+	for i, tt := range tests {
+		claims := map[string]any{"sub": "uid"}
+		for k, v := range tt.claims {
+			claims[k] = v
+		}
+		w := serveBearer(h, sign(claims))
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+
+	h = withFirebaseAuth(fauth.RequireOneTimeUse("download_id", brokenGuard{})(func(w http.ResponseWriter, r *http.Request) {}))
+	if w := serveBearer(h, sign(map[string]any{"sub": "uid", "download_id": "d3"})); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestMemoryReplayGuard(t *testing.T) {
+	ctx := context.Background()
+	g := fauth.NewMemoryReplayGuard()
+	if ok, _ := g.Use(ctx, "k", time.Now().Add(time.Hour)); !ok {
+		t.Fatal("unused keys should be accepted")
+	}
+	if ok, _ := g.Use(ctx, "k", time.Now().Add(time.Hour)); ok {
+		t.Fatal("used keys should be rejected")
+	}
+	if ok, _ := g.Use(ctx, "expired", time.Now().Add(-time.Second)); !ok {
+		t.Fatal("unused keys should be accepted")
+	}
+	if ok, _ := g.Use(ctx, "expired", time.Now().Add(time.Hour)); !ok {
+		t.Fatal("expired keys should be accepted again")
+	}
+}
@@ -0,0 +1,63 @@
+package fauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const requestIDContextKey contextKey = "request_id"
+
+// maxRequestIDLength bounds the length of the request IDs propagated from the `Engine.RequestIDHeader`.
+const maxRequestIDLength = 128
+
+// WithRequestID returns a copy of the `context.Context` with the given request ID.
+// To retrieve it, use the `RequestID` func.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestID returns the ID of the request, propagated or generated when `Engine.RequestIDHeader` is set.
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDContextKey).(string)
+	return id, ok && id != ""
+}
+
+// withRequestID stores the ID of the request, read from the `Engine.RequestIDHeader` or generated,
+// in the request context and echoes it in the response headers, if any. The IDs sent by the client are
+// replaced by generated ones unless they're valid, see `validRequestID`, as they end up in the logs.
+func (e *Engine) withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	if e.RequestIDHeader == "" {
+		return r
+	}
+	id := r.Header.Get(e.RequestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	if w != nil {
+		w.Header().Set(e.RequestIDHeader, id)
+	}
+	return r.WithContext(WithRequestID(r.Context(), id))
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether the ID is non-empty, at most `maxRequestIDLength` bytes long
+// and only made of `[A-Za-z0-9._-]`.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package fauth_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestRequestID(t *testing.T) {
+	if _, ok := fauth.RequestID(context.Background()); ok {
+		t.Fatal("request ID shouldn't be present")
+	}
+
+	var buf bytes.Buffer
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.RequestIDHeader = "X-Request-ID"
+		e.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	})
+	var id string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		id, _ = fauth.RequestID(r.Context())
+		fauth.Logger(r.Context()).Info("hello")
+	})
+	jwt := sign(map[string]any{"sub": "uid"})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Bearer "+jwt)
+	r.Header.Set("X-Request-ID", "abc")
+	h.ServeHTTP(w, r)
+	if id != "abc" || w.Header().Get("X-Request-ID") != "abc" {
+		t.Fatalf("the request ID should be propagated, got: %s", id)
+	}
+	if out := buf.String(); !strings.Contains(out, "request_id=abc") || !strings.Contains(out, "uid=uid") {
+		t.Fatalf("the logger should carry the request ID: %s", out)
+	}
+
+	w = serveBearer(h, jwt)
+	if id == "" || id == "abc" || w.Header().Get("X-Request-ID") != id {
+		t.Fatalf("a request ID should be generated, got: %s", id)
+	}
+}
+
+func TestRequestIDInvalid(t *testing.T) {
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.RequestIDHeader = "X-Request-ID"
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"3f2a-41.b_c", true},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+		{"abc def", false},
+		{"abc\x1b[31m", false},
+		{`"><script>`, false},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("X-Request-ID", tt.header)
+		h.ServeHTTP(w, r)
+		got := w.Header().Get("X-Request-ID")
+		if (got == tt.header) != tt.valid || got == "" {
+			t.Fatalf("%d: unexpected request ID: %q", i, got)
+		}
+	}
+}
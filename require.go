@@ -0,0 +1,430 @@
+package fauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// require returns a middleware func running the check against the verified token.
+// It's meant to be used after `Auth`; failures are passed to the `Engine.OnErr` func.
+func require(check func(r *http.Request, token *auth.Token) error) func(http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := AuthToken(r.Context())
+			if !ok || token == nil {
+				fail(w, r, errNoAuthToken)
+				return
+			}
+			if err := check(r, token); err != nil {
+				fail(w, r, err)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+	}
+}
+
+// RequireClaimPresent returns a middleware func rejecting the request with 403 Forbidden
+// when any of the given claims is absent from the verified token, regardless of its value.
+// Nested claims can be referenced using the dot notation, e.g. `org.id`.
+//
+//	withTenant := fauth.RequireClaimPresent("tenant")
+//	http.HandleFunc("/private", withFirebaseAuth(withTenant(handler)))
+func RequireClaimPresent(keys ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		for _, key := range keys {
+			if _, ok := claims.Get(key); !ok {
+				return forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, key))
+			}
+		}
+		return nil
+	})
+}
+
+// RequireAudience returns a middleware func rejecting the request with 403 Forbidden, and `ErrWrongAudience`,
+// unless the audience of the verified token includes aud.
+//
+// The Firebase Admin SDK already makes sure ID tokens are issued for your Firebase project. RequireAudience is
+// meant for resource servers whose clients obtain tokens scoped to them, e.g. by a verifier keeping the `aud`
+// claim, which can be either a string or an array of strings.
+func RequireAudience(aud string) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		for _, a := range audiences(token) {
+			if a == aud {
+				return nil
+			}
+		}
+		return forbidden(fmt.Errorf("%w: expected %s", ErrWrongAudience, aud))
+	})
+}
+
+// checkAudience makes sure the audience of the verified token includes one of the audiences
+// returned by the `Engine.AudienceFunc`, if set.
+func (e *Engine) checkAudience(r *http.Request, data any) error {
+	if e.AudienceFunc == nil {
+		return nil
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return nil
+	}
+	accepted := e.AudienceFunc(r)
+	for _, a := range audiences(token) {
+		for _, aud := range accepted {
+			if a == aud {
+				return nil
+			}
+		}
+	}
+	return forbidden(fmt.Errorf("%w: expected one of %s", ErrWrongAudience, strings.Join(accepted, ", ")))
+}
+
+// audiences returns the audience of the token along with the ones found in its `aud` claim.
+func audiences(token *auth.Token) []string {
+	var aud []string
+	if token.Audience != "" {
+		aud = append(aud, token.Audience)
+	}
+	switch v := token.Claims["aud"].(type) {
+	case string:
+		aud = append(aud, v)
+	case []string:
+		aud = append(aud, v...)
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+	}
+	return aud
+}
+
+// RequireUIDFunc returns a middleware func rejecting the request with 403 Forbidden, and `ErrUIDNotAllowed`,
+// unless the allowed func reports the UID of the verified token is allowed, e.g. for invite-only betas.
+// Errors returned by the allowed func are answered with 503 Service Unavailable.
+//
+// The allowed func runs on every request, so if it hits a database, consider caching its results
+// for a short while, keeping in mind that revoking access then takes up to the cache TTL.
+func RequireUIDFunc(allowed func(uid string) (bool, error)) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		ok, err := allowed(token.UID)
+		if err != nil {
+			return unavailable(fmt.Errorf("fauth: failed to check the uid: %w", err))
+		}
+		if !ok {
+			return forbidden(fmt.Errorf("%w: %s", ErrUIDNotAllowed, token.UID))
+		}
+		return nil
+	})
+}
+
+// RequirePlatform returns a middleware func rejecting the request with 403 Forbidden unless the `platform`
+// custom claim of the verified token, e.g. `ios`, `android` or `web`, is one of the given platforms.
+// The comparison is case-insensitive. Tokens without the claim are rejected with `ErrMissingClaim`,
+// the ones with a different platform with `ErrPlatformNotAllowed`.
+func RequirePlatform(platforms ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		platform, ok := claims.String("platform")
+		if !ok {
+			return forbidden(fmt.Errorf("%w: platform", ErrMissingClaim))
+		}
+		for _, p := range platforms {
+			if strings.EqualFold(p, platform) {
+				return nil
+			}
+		}
+		return forbidden(fmt.Errorf("%w: %s", ErrPlatformNotAllowed, platform))
+	})
+}
+
+// RequireVerifiedClaim returns a middleware func that requires the email of the token to be verified
+// and the claim with the given key to equal the given value, e.g. for verified admins:
+//
+//	withVerifiedAdmin := fauth.RequireVerifiedClaim("role", "admin")
+//	http.HandleFunc("/admin", withFirebaseAuth(withVerifiedAdmin(handler)))
+//
+// Each failure is reported with its own error, `ErrEmailNotVerified`, `ErrMissingClaim` or `ErrClaimMismatch`,
+// all answered with 403 Forbidden. Numbers are compared by value, so `1` matches the `1.0` decoded from the token.
+// Like the other `Require*` funcs, it must run after `Auth`.
+func RequireVerifiedClaim(key string, value any) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		if verified, _ := claims.Bool("email_verified"); !verified {
+			return forbidden(ErrEmailNotVerified)
+		}
+		v, ok := claims.Get(key)
+		if !ok {
+			return forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, key))
+		}
+		if !claimEqual(v, value) {
+			return forbidden(fmt.Errorf("%w: %s", ErrClaimMismatch, key))
+		}
+		return nil
+	})
+}
+
+// RequireEmailVerified returns a middleware func rejecting the request with 403 Forbidden unless the email
+// of the verified token has been verified:
+//
+//	http.HandleFunc("/checkout", withFirebaseAuth(fauth.RequireEmailVerified()(handler)))
+//
+// Tokens without an email, e.g. issued to users signed in with their phone number or anonymously,
+// are rejected with `ErrMissingClaim`, the ones whose `email_verified` claim is false or missing
+// with `ErrEmailNotVerified`.
+func RequireEmailVerified() func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		if email, _ := claims.String("email"); email == "" {
+			return forbidden(fmt.Errorf("%w: email, the user signed in without one", ErrMissingClaim))
+		}
+		if verified, _ := claims.Bool("email_verified"); !verified {
+			return forbidden(ErrEmailNotVerified)
+		}
+		return nil
+	})
+}
+
+// RequireEmailDomain returns a middleware func rejecting the request with 403 Forbidden unless the email
+// of the verified token belongs to one of the domains, e.g. to keep an admin API to the employees:
+//
+//	withEmployees := fauth.RequireEmailDomain("ourcompany.com")
+//	http.HandleFunc("/admin", withFirebaseAuth(fauth.RequireEmailVerified()(withEmployees(handler))))
+//
+// The domains are compared case-insensitively to the part of the email after its last `@`, and must match
+// exactly: subdomains, e.g. `eu.ourcompany.com`, must be listed on their own. The domains may be given with
+// their leading `@`, e.g. `@ourcompany.com`. Tokens without an email are rejected
+// with `ErrMissingClaim`, the emails without an `@` or from other domains with `ErrClaimMismatch`.
+//
+// Unless the sign-in providers verify the emails, anyone can sign up with an address of the domain:
+// compose it with `RequireEmailVerified`, as above.
+func RequireEmailDomain(domains ...string) func(http.HandlerFunc) http.HandlerFunc {
+	allowed := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		allowed[strings.ToLower(strings.TrimPrefix(d, "@"))] = true
+	}
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		email, _ := claims.String("email")
+		if email == "" {
+			return forbidden(fmt.Errorf("%w: email", ErrMissingClaim))
+		}
+		i := strings.LastIndex(email, "@")
+		if i < 0 {
+			return forbidden(fmt.Errorf("%w: email without a domain", ErrClaimMismatch))
+		}
+		if !allowed[strings.ToLower(email[i+1:])] {
+			return forbidden(fmt.Errorf("%w: email domain not allowed: %s", ErrClaimMismatch, email[i+1:]))
+		}
+		return nil
+	})
+}
+
+// RequireClaims wraps the `Engine.OnAuth` func, asserting the verified token carries the required claims
+// with the given values, e.g. to reject non-admins before any other check:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnAuth = fauth.RequireClaims(map[string]any{"admin": true})(fauth.VerifyIDToken)
+//	})
+//
+// Missing claims are rejected with `ErrMissingClaim`, the ones with another value, or of another type,
+// with `ErrClaimMismatch`, both answered with 403 Forbidden rather than the 401 of invalid tokens.
+// Keys and values are matched like in `RequireVerifiedClaim`. As it runs before the `Engine.ClaimNamespace`
+// is stripped, the keys must include the namespace.
+func RequireClaims(required map[string]any) func(next OnAuthFunc) OnAuthFunc {
+	keys := make([]string, 0, len(required))
+	for key := range required {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return func(next OnAuthFunc) OnAuthFunc {
+		return func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			data, err := next(r, app, client)
+			if err != nil {
+				return nil, err
+			}
+			token, ok := tokenOf(data)
+			if !ok || token == nil {
+				return nil, errNoAuthToken
+			}
+			claims := Claims(token.Claims)
+			for _, key := range keys {
+				v, ok := claims.Get(key)
+				if !ok {
+					return nil, forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, key))
+				}
+				if !claimEqual(v, required[key]) {
+					return nil, forbidden(fmt.Errorf("%w: %s", ErrClaimMismatch, key))
+				}
+			}
+			return data, nil
+		}
+	}
+}
+
+// claimEqual reports whether the decoded claim equals the expected value,
+// normalizing the numbers to float64 the way the JSON decoder does.
+func claimEqual(claim, value any) bool {
+	if f, ok := toFloat(value); ok {
+		c, ok := claim.(float64)
+		return ok && c == f
+	}
+	if value == nil || !reflect.TypeOf(value).Comparable() {
+		return reflect.DeepEqual(claim, value)
+	}
+	return claim == value
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// RequireAnyRole returns a middleware func rejecting the request with 403 Forbidden, and `ErrMissingRole`,
+// unless the user has at least one of the given roles, as returned by `Roles`.
+// Tokens without the roles claim are rejected with `ErrMissingClaim`.
+//
+//	withStaff := fauth.RequireAnyRole("admin", "support")
+//	http.HandleFunc("/tickets", withFirebaseAuth(withStaff(handler)))
+func RequireAnyRole(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return requireRoles(func(has map[string]bool) error {
+		for _, role := range roles {
+			if has[role] {
+				return nil
+			}
+		}
+		return forbidden(fmt.Errorf("%w: any of %s", ErrMissingRole, strings.Join(roles, ", ")))
+	})
+}
+
+// RequireAllRoles is like `RequireAnyRole`, but the user must have all the given roles.
+func RequireAllRoles(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return requireRoles(func(has map[string]bool) error {
+		for _, role := range roles {
+			if !has[role] {
+				return forbidden(fmt.Errorf("%w: %s", ErrMissingRole, role))
+			}
+		}
+		return nil
+	})
+}
+
+func requireRoles(check func(has map[string]bool) error) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		roles, ok := Roles(r.Context())
+		if !ok {
+			return forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, scopeFrom(r.Context()).engine.RolesClaim))
+		}
+		has := make(map[string]bool, len(roles))
+		for _, role := range roles {
+			has[role] = true
+		}
+		return check(has)
+	})
+}
+
+// RequireClaimsConsistent returns a middleware func rejecting the request with 403 Forbidden unless the claims
+// with the given keys are equal, e.g. to catch stale tokens after an email change by comparing the `email` claim
+// with a copy set server-side in a custom claim. Missing claims, null ones included, are rejected
+// with `ErrMissingClaim`, mismatching ones with `ErrClaimMismatch`.
+//
+//	withConsistentEmail := fauth.RequireClaimsConsistent("email", "account.email")
+func RequireClaimsConsistent(a, b string) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		va, _ := claims.Get(a)
+		if va == nil {
+			return forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, a))
+		}
+		vb, _ := claims.Get(b)
+		if vb == nil {
+			return forbidden(fmt.Errorf("%w: %s", ErrMissingClaim, b))
+		}
+		if !claimEqual(va, vb) {
+			return forbidden(fmt.Errorf("%w: %s and %s", ErrClaimMismatch, a, b))
+		}
+		return nil
+	})
+}
+
+// RequireAccountAge returns a middleware func rejecting the request with 403 Forbidden, and `ErrAccountTooNew`,
+// when the account of the user was created less than min ago, e.g. to keep fresh accounts away from abuse-prone actions.
+//
+// The creation time is part of the user record, not of the token, so it depends on the `VerifyIDTokenWithUser`
+// verifier fetching it; consider setting the `Engine.UserCacheTTL` to avoid an RPC per request.
+// Without the user record, the request is rejected with 500 Internal Server Error.
+func RequireAccountAge(min time.Duration) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		user, ok := User(r.Context())
+		if !ok || user.UserMetadata == nil {
+			return withStatus(http.StatusInternalServerError, errors.New("fauth: no user record, use VerifyIDTokenWithUser"))
+		}
+		created := time.UnixMilli(user.UserMetadata.CreationTimestamp)
+		if age := Now(r.Context()).Sub(created); age < min {
+			return forbidden(fmt.Errorf("%w: created %s ago", ErrAccountTooNew, age.Round(time.Second)))
+		}
+		return nil
+	})
+}
+
+// RequireTOSVersion returns a middleware func rejecting the request with 451 Unavailable For Legal Reasons,
+// and `ErrTOSNotAccepted`, unless the claim with the given key equals the current version of the terms of service,
+// e.g. a `tos_version` custom claim set when the user accepts them:
+//
+//	withTOS := fauth.RequireTOSVersion("2024-06", "tos_version")
+//	http.HandleFunc("/private", withFirebaseAuth(withTOS(handler)))
+//
+// A missing claim counts as not accepted. Numeric claims are compared by their decimal representation,
+// so the `3` decoded from the token matches the version "3".
+//
+// Clients should treat the `tos_not_accepted` code of the `X-Auth-Error` header as a prompt to show the current terms.
+// Once the user accepts them, the backend updates the custom claim, e.g. using `auth.Client.SetCustomUserClaims`,
+// and the client forces a token refresh, e.g. `getIdToken(true)`, before retrying: the claims of the tokens issued
+// before the update are left unchanged. To answer with another status, match `ErrTOSNotAccepted` in the `Engine.OnErr`.
+func RequireTOSVersion(current string, claimKey string) func(http.HandlerFunc) http.HandlerFunc {
+	return require(func(r *http.Request, token *auth.Token) error {
+		claims, _ := ClaimsFromContext(r.Context())
+		var accepted string
+		switch v, _ := claims.Get(claimKey); v := v.(type) {
+		case string:
+			accepted = v
+		case float64:
+			accepted = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		if accepted == "" {
+			return withStatus(http.StatusUnavailableForLegalReasons, fmt.Errorf("%w: %s missing", ErrTOSNotAccepted, claimKey))
+		}
+		if accepted != current {
+			return withStatus(http.StatusUnavailableForLegalReasons, fmt.Errorf("%w: accepted %s, current %s", ErrTOSNotAccepted, accepted, current))
+		}
+		return nil
+	})
+}
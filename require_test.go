@@ -0,0 +1,333 @@
+package fauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func serveWithToken(mw func(http.HandlerFunc) http.HandlerFunc, token *auth.Token) int {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	if token != nil {
+		r = r.WithContext(fauth.WithAuthData(context.Background(), token))
+	}
+	mw(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+	return w.Code
+}
+
+func TestRequireClaimPresent(t *testing.T) {
+	token := &auth.Token{Claims: map[string]any{
+		"tenant": "acme",
+		"org":    map[string]any{"id": "42"},
+		"flag":   nil,
+	}}
+	tests := []struct {
+		keys []string
+		code int
+	}{
+		{[]string{"tenant"}, http.StatusOK},
+		{[]string{"tenant", "org.id"}, http.StatusOK},
+		{[]string{"flag"}, http.StatusOK},
+		{[]string{"role"}, http.StatusForbidden},
+		{[]string{"tenant", "org.name"}, http.StatusForbidden},
+		{[]string{"tenant.id"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := serveWithToken(fauth.RequireClaimPresent(tt.keys...), token); code != tt.code {
+			t.Fatalf("%v: expected %d, got %d", tt.keys, tt.code, code)
+		}
+	}
+	if code := serveWithToken(fauth.RequireClaimPresent("tenant"), nil); code != http.StatusUnauthorized {
+		t.Fatalf("missing token: expected 401, got %d", code)
+	}
+}
+
+func TestRequireAudience(t *testing.T) {
+	tests := []struct {
+		token *auth.Token
+		code  int
+	}{
+		{&auth.Token{Audience: "api"}, http.StatusOK},
+		{&auth.Token{Audience: "project"}, http.StatusForbidden},
+		{&auth.Token{Claims: map[string]any{"aud": "api"}}, http.StatusOK},
+		{&auth.Token{Claims: map[string]any{"aud": []any{"web", "api"}}}, http.StatusOK},
+		{&auth.Token{Claims: map[string]any{"aud": []any{"web", 1.0}}}, http.StatusForbidden},
+		{&auth.Token{}, http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		if code := serveWithToken(fauth.RequireAudience("api"), tt.token); code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, code)
+		}
+	}
+}
+
+func TestRequireUIDFunc(t *testing.T) {
+	mw := fauth.RequireUIDFunc(func(uid string) (bool, error) {
+		switch uid {
+		case "invited":
+			return true, nil
+		case "broken":
+			return false, errors.New("db is down")
+		}
+		return false, nil
+	})
+	for uid, code := range map[string]int{
+		"invited": http.StatusOK,
+		"other":   http.StatusForbidden,
+		"broken":  http.StatusServiceUnavailable,
+	} {
+		if c := serveWithToken(mw, &auth.Token{UID: uid}); c != code {
+			t.Fatalf("%s: expected %d, got %d", uid, code, c)
+		}
+	}
+}
+
+func TestRequirePlatform(t *testing.T) {
+	mw := fauth.RequirePlatform("ios", "android")
+	tests := []struct {
+		claims map[string]any
+		code   int
+	}{
+		{map[string]any{"platform": "ios"}, http.StatusOK},
+		{map[string]any{"platform": "Android"}, http.StatusOK},
+		{map[string]any{"platform": "web"}, http.StatusForbidden},
+		{map[string]any{"platform": 1.0}, http.StatusForbidden},
+		{map[string]any{}, http.StatusForbidden},
+		{nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := serveWithToken(mw, &auth.Token{Claims: tt.claims}); code != tt.code {
+			t.Fatalf("%v: expected %d, got %d", tt.claims, tt.code, code)
+		}
+	}
+}
+
+func TestRequireVerifiedClaim(t *testing.T) {
+	tests := []struct {
+		claims map[string]any
+		key    string
+		value  any
+		err    string
+	}{
+		{map[string]any{"email_verified": true, "role": "admin"}, "role", "admin", ""},
+		{map[string]any{"email_verified": true, "level": 3.0}, "level", 3, ""},
+		{map[string]any{"email_verified": true, "org": map[string]any{"id": "42"}}, "org.id", "42", ""},
+		{map[string]any{"email_verified": true, "tags": []any{"a"}}, "tags", []any{"a"}, ""},
+		{map[string]any{"email_verified": false, "role": "admin"}, "role", "admin", "email_not_verified"},
+		{map[string]any{"role": "admin"}, "role", "admin", "email_not_verified"},
+		{map[string]any{"email_verified": true}, "role", "admin", "missing_claim"},
+		{map[string]any{"email_verified": true, "role": "editor"}, "role", "admin", "claim_mismatch"},
+		{map[string]any{"email_verified": true, "level": "3"}, "level", 3, "claim_mismatch"},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		r = r.WithContext(fauth.WithAuthData(context.Background(), &auth.Token{Claims: tt.claims}))
+		fauth.RequireVerifiedClaim(tt.key, tt.value)(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+
+		code := http.StatusOK
+		if tt.err != "" {
+			code = http.StatusForbidden
+		}
+		if w.Code != code {
+			t.Fatalf("%d: expected %d, got %d", i, code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+}
+
+func TestRequireEmailVerified(t *testing.T) {
+	tests := []struct {
+		claims map[string]any
+		code   int
+	}{
+		{map[string]any{"email": "a@example.com", "email_verified": true}, http.StatusOK},
+		{map[string]any{"email": "a@example.com", "email_verified": false}, http.StatusForbidden},
+		{map[string]any{"email": "a@example.com"}, http.StatusForbidden},
+		{map[string]any{"phone_number": "+15555550100"}, http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		if code := serveWithToken(fauth.RequireEmailVerified(), &auth.Token{Claims: tt.claims}); code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, code)
+		}
+	}
+}
+
+func TestRequireEmailDomain(t *testing.T) {
+	tests := []struct {
+		email any
+		code  int
+	}{
+		{"alice@ourcompany.com", http.StatusOK},
+		{"Bob@OurCompany.COM", http.StatusOK},
+		{"carol@partner.io", http.StatusOK},
+		{"dave@eu.ourcompany.com", http.StatusForbidden},
+		{"eve@ourcompany.com.evil.io", http.StatusForbidden},
+		{"ourcompany.com", http.StatusForbidden},
+		{"", http.StatusForbidden},
+		{42, http.StatusForbidden},
+		{nil, http.StatusForbidden},
+	}
+	mw := fauth.RequireEmailDomain("ourcompany.com", "@Partner.io")
+	for i, tt := range tests {
+		claims := map[string]any{}
+		if tt.email != nil {
+			claims["email"] = tt.email
+		}
+		if code := serveWithToken(mw, &auth.Token{Claims: claims}); code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, code)
+		}
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		mw   func(http.HandlerFunc) http.HandlerFunc
+		code int
+	}{
+		{fauth.RequireAnyRole("admin", "billing"), http.StatusOK},
+		{fauth.RequireAnyRole("billing", "support"), http.StatusOK},
+		{fauth.RequireAnyRole("owner"), http.StatusForbidden},
+		{fauth.RequireAnyRole(), http.StatusForbidden},
+		{fauth.RequireAllRoles("admin", "support"), http.StatusOK},
+		{fauth.RequireAllRoles("admin", "billing"), http.StatusForbidden},
+		{fauth.RequireAllRoles(), http.StatusOK},
+	}
+	token := &auth.Token{Claims: map[string]any{"roles": []any{"admin", "support"}}}
+	for i, tt := range tests {
+		if code := serveWithToken(tt.mw, token); code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, code)
+		}
+	}
+
+	for _, claims := range []map[string]any{{}, {"roles": []any{}}} {
+		token := &auth.Token{Claims: claims}
+		if code := serveWithToken(fauth.RequireAnyRole("admin"), token); code != http.StatusForbidden {
+			t.Fatalf("%v: expected 403, got %d", claims, code)
+		}
+		if code := serveWithToken(fauth.RequireAllRoles("admin"), token); code != http.StatusForbidden {
+			t.Fatalf("%v: expected 403, got %d", claims, code)
+		}
+	}
+}
+
+func TestRequireClaimsConsistent(t *testing.T) {
+	mw := fauth.RequireClaimsConsistent("email", "account.email")
+	tests := []struct {
+		claims map[string]any
+		code   int
+	}{
+		{map[string]any{"email": "a@example.com", "account": map[string]any{"email": "a@example.com"}}, http.StatusOK},
+		{map[string]any{"email": "b@example.com", "account": map[string]any{"email": "a@example.com"}}, http.StatusForbidden},
+		{map[string]any{"email": "a@example.com"}, http.StatusForbidden},
+		{map[string]any{"account": map[string]any{"email": "a@example.com"}}, http.StatusForbidden},
+		{map[string]any{"email": nil, "account": map[string]any{"email": nil}}, http.StatusForbidden},
+		{nil, http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		if code := serveWithToken(mw, &auth.Token{Claims: tt.claims}); code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, code)
+		}
+	}
+}
+
+func TestAudienceFunc(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.AudienceFunc = func(r *http.Request) []string {
+			product, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+			return []string{product}
+		}
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		path string
+		aud  string
+		code int
+	}{
+		{"/billing/invoices", "billing", http.StatusOK},
+		{"/chat/rooms", "billing", http.StatusForbidden},
+		{"/chat/rooms", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tt.path, nil)
+		r.Header.Set("Authorization", "Bearer "+sign(map[string]any{"sub": "uid", "aud": tt.aud}))
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%s, %s: expected %d, got %d", tt.path, tt.aud, tt.code, w.Code)
+		}
+	}
+}
+
+func TestRequireClaims(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.OnAuth = fauth.RequireClaims(map[string]any{"admin": true, "org.tier": 2})(e.OnAuth)
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		claims map[string]any
+		code   int
+		err    string
+	}{
+		{map[string]any{"admin": true, "org": map[string]any{"tier": 2}}, http.StatusOK, ""},
+		{map[string]any{"admin": false, "org": map[string]any{"tier": 2}}, http.StatusForbidden, "claim_mismatch"},
+		{map[string]any{"admin": "true", "org": map[string]any{"tier": 2}}, http.StatusForbidden, "claim_mismatch"},
+		{map[string]any{"admin": true, "org": map[string]any{"tier": "2"}}, http.StatusForbidden, "claim_mismatch"},
+		{map[string]any{"admin": true}, http.StatusForbidden, "missing_claim"},
+		{map[string]any{}, http.StatusForbidden, "missing_claim"},
+	}
+	for i, tt := range tests {
+		claims := map[string]any{"sub": "uid"}
+		for k, v := range tt.claims {
+			claims[k] = v
+		}
+		w := serveBearer(h, sign(claims))
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+	if w := serveBearer(h, "invalid"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid token: expected 401, got %d", w.Code)
+	}
+
+	nilClaims := fauth.RequireClaims(map[string]any{"admin": true})(func(*http.Request, *firebase.App, *auth.Client) (any, error) {
+		return &auth.Token{UID: "uid"}, nil
+	})
+	if _, err := nilClaims(httptest.NewRequest(http.MethodGet, "http://www.example.com", nil), nil, nil); !errors.Is(err, fauth.ErrMissingClaim) {
+		t.Fatalf("expected ErrMissingClaim, got %v", err)
+	}
+}
+
+func TestRequireTOSVersion(t *testing.T) {
+	tests := []struct {
+		claims map[string]any
+		code   int
+	}{
+		{map[string]any{"tos_version": "2024-06"}, http.StatusOK},
+		{map[string]any{"tos_version": "2023-01"}, http.StatusUnavailableForLegalReasons},
+		{map[string]any{"tos_version": ""}, http.StatusUnavailableForLegalReasons},
+		{map[string]any{"tos_version": true}, http.StatusUnavailableForLegalReasons},
+		{map[string]any{}, http.StatusUnavailableForLegalReasons},
+	}
+	for i, tt := range tests {
+		if code := serveWithToken(fauth.RequireTOSVersion("2024-06", "tos_version"), &auth.Token{Claims: tt.claims}); code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, code)
+		}
+	}
+	if code := serveWithToken(fauth.RequireTOSVersion("3", "tos"), &auth.Token{Claims: map[string]any{"tos": 3.0}}); code != http.StatusOK {
+		t.Fatalf("numeric version: expected 200, got %d", code)
+	}
+}
@@ -0,0 +1,108 @@
+package fauth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// Result bundles the per-request auth state: the verified token, along with the raw JWT,
+// where it was read from and how long the verification took.
+// The `Engine.OnAuth` func can return it instead of the bare `*auth.Token`, e.g. using `VerifyIDTokenResult`.
+// It embeds the token, so the accessors like `AuthToken` keep working.
+type Result struct {
+	*auth.Token
+	// JWT is the raw, encoded token.
+	JWT string
+	// Source describes where the token was read from, e.g. `header`.
+	Source string
+	// VerifiedAt is the time the verification completed, according to the `Engine.Now` clock.
+	VerifiedAt time.Time
+	// Duration is how long the verification took.
+	Duration time.Duration
+	// User is the user record of the token, fetched by `VerifyIDTokenWithUser`.
+	User *auth.UserRecord
+
+	rolesClaim string
+}
+
+// AuthResult returns the Result stored by the middleware, if the `Engine.OnAuth` func returned one.
+func AuthResult(ctx context.Context) (*Result, bool) {
+	res, ok := AuthData(ctx).(*Result)
+	return res, ok && res != nil
+}
+
+// VerifyIDTokenResult is like `VerifyIDToken`, but it returns a `*Result`:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnAuth = fauth.VerifyIDTokenResult
+//	})
+func VerifyIDTokenResult(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	return verifyResult(r, client)
+}
+
+func verifyResult(r *http.Request, client *auth.Client) (*Result, error) {
+	jwt, err := ExtractToken(r)
+	if err != nil {
+		return nil, err
+	}
+	token, err := verifyIDToken(r.Context(), client, jwt, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Token: token, JWT: jwt, Source: "header"}, nil
+}
+
+// UID returns the UID of the verified token, or an empty string if there's none.
+func (res *Result) UID() string {
+	if res == nil || res.Token == nil {
+		return ""
+	}
+	return res.Token.UID
+}
+
+// Claim returns the claim of the verified token with the given key.
+// Nested claims can be referenced using the dot notation, e.g. `org.id`.
+func (res *Result) Claim(key string) (any, bool) {
+	if res == nil || res.Token == nil {
+		return nil, false
+	}
+	return Claims(res.Claims).Get(key)
+}
+
+// HasRole reports whether the user has the given role, read from the `Engine.RolesClaim` like `Roles` does.
+func (res *Result) HasRole(role string) bool {
+	if res == nil || res.Token == nil {
+		return false
+	}
+	key := res.rolesClaim
+	if key == "" {
+		key = defaultRolesClaim
+	}
+	roles, _ := rolesOf(res.Claims, key)
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// complete fills in the fields of the Result the `Engine.OnAuth` func left empty.
+func (e *Engine) complete(data any, start time.Time) {
+	res, ok := data.(*Result)
+	if !ok || res == nil {
+		return
+	}
+	now := e.Now()
+	if res.VerifiedAt.IsZero() {
+		res.VerifiedAt = now
+	}
+	if res.Duration == 0 {
+		res.Duration = now.Sub(start)
+	}
+	res.rolesClaim = e.RolesClaim
+}
@@ -0,0 +1,74 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestResult(t *testing.T) {
+	frozen := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.RolesClaim = "app.roles"
+		e.Now = func() time.Time { return frozen }
+		e.OnAuth = func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+			jwt, err := fauth.ExtractToken(r)
+			if err != nil {
+				return nil, err
+			}
+			token, err := fauthtest.NewVerifier(&testKey.PublicKey).VerifyIDToken(r.Context(), jwt)
+			if err != nil {
+				return nil, err
+			}
+			return &fauth.Result{Token: token, JWT: jwt, Source: "header"}, nil
+		}
+	})
+
+	var (
+		res   *fauth.Result
+		token *auth.Token
+	)
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		res, _ = fauth.AuthResult(r.Context())
+		token, _ = fauth.AuthToken(r.Context())
+	})
+	jwt := sign(map[string]any{"sub": "uid", "app": map[string]any{"roles": []any{"admin"}}})
+	if w := serveBearer(h, jwt); w.Code != http.StatusOK {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+
+	if res == nil || res.JWT != jwt || res.Source != "header" || !res.VerifiedAt.Equal(frozen) {
+		t.Fatalf("invalid result: %+v", res)
+	}
+	if token == nil || token != res.Token {
+		t.Fatal("AuthToken should unwrap the result")
+	}
+	if res.UID() != "uid" {
+		t.Fatalf("invalid uid: %s", res.UID())
+	}
+	if v, ok := res.Claim("app.roles"); !ok || len(v.([]any)) != 1 {
+		t.Fatalf("invalid claim: %v", v)
+	}
+	if !res.HasRole("admin") || res.HasRole("billing") {
+		t.Fatal("the roles should be read from the RolesClaim")
+	}
+}
+
+func TestResultNil(t *testing.T) {
+	var res *fauth.Result
+	if res.UID() != "" || res.HasRole("admin") {
+		t.Fatal("a nil result shouldn't carry anything")
+	}
+	if _, ok := res.Claim("role"); ok {
+		t.Fatal("a nil result shouldn't carry claims")
+	}
+	if _, ok := fauth.AuthResult(context.Background()); ok {
+		t.Fatal("the result shouldn't be present")
+	}
+}
@@ -0,0 +1,38 @@
+package fauth
+
+import (
+	"context"
+)
+
+const defaultRolesClaim = "roles"
+
+// Roles returns the roles of the verified token, read from the `Engine.RolesClaim`, e.g. `roles: ["admin", "billing"]`.
+// Both the `[]any` shape decoded from JSON and `[]string` are supported; non-string roles are skipped.
+// It returns false when the claim is absent or isn't an array.
+func Roles(ctx context.Context) ([]string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return rolesOf(claims, scopeFrom(ctx).engine.RolesClaim)
+}
+
+func rolesOf(claims Claims, key string) ([]string, bool) {
+	v, ok := claims.Get(key)
+	if !ok {
+		return nil, false
+	}
+	switch v := v.(type) {
+	case []string:
+		return v, true
+	case []any:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles, true
+	}
+	return nil, false
+}
@@ -0,0 +1,47 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestRoles(t *testing.T) {
+	tests := []struct {
+		claims map[string]any
+		roles  []string
+		ok     bool
+	}{
+		{map[string]any{"roles": []any{"admin", "billing"}}, []string{"admin", "billing"}, true},
+		{map[string]any{"roles": []string{"admin"}}, []string{"admin"}, true},
+		{map[string]any{"roles": []any{"admin", 1.0}}, []string{"admin"}, true},
+		{map[string]any{"roles": []any{}}, []string{}, true},
+		{map[string]any{"roles": "admin"}, nil, false},
+		{map[string]any{}, nil, false},
+	}
+	for _, tt := range tests {
+		ctx := fauth.WithAuthData(context.Background(), &auth.Token{Claims: tt.claims})
+		roles, ok := fauth.Roles(ctx)
+		if ok != tt.ok || !reflect.DeepEqual(roles, tt.roles) {
+			t.Fatalf("%v: expected %v, got %v", tt.claims, tt.roles, roles)
+		}
+	}
+}
+
+func TestRolesClaim(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.RolesClaim = "app.roles"
+	})
+	var roles []string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		roles, _ = fauth.Roles(r.Context())
+	})
+	serveBearer(h, sign(map[string]any{"sub": "uid", "app": map[string]any{"roles": []string{"admin"}}}))
+	if !reflect.DeepEqual(roles, []string{"admin"}) {
+		t.Fatalf("invalid roles: %v", roles)
+	}
+}
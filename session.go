@@ -0,0 +1,45 @@
+package fauth
+
+import (
+	"context"
+	"net/http"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// VerifySessionCookie returns an `Engine.OnAuth` func verifying the Firebase session cookie with the given name,
+// e.g. minted by `LoginHandler`, and returning its `*auth.Token` like `VerifyIDToken`:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnAuth = fauth.VerifySessionCookie("session")
+//	})
+//
+// It doesn't check whether the session has been revoked, use `VerifySessionCookieAndCheckRevoked` if needed.
+func VerifySessionCookie(cookieName string) func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	return verifySessionCookie(cookieName, (*auth.Client).VerifySessionCookie)
+}
+
+// VerifySessionCookieAndCheckRevoked is like `VerifySessionCookie`, but it also checks the session hasn't been revoked.
+// Like `VerifyIDTokenAndCheckRevoked`, it makes an RPC call to perform the revocation check.
+func VerifySessionCookieAndCheckRevoked(cookieName string) func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	return verifySessionCookie(cookieName, (*auth.Client).VerifySessionCookieAndCheckRevoked)
+}
+
+func verifySessionCookie(
+	cookieName string,
+	verify func(client *auth.Client, ctx context.Context, cookie string) (*auth.Token, error),
+) func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	extract := FromCookie(cookieName)
+	return func(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+		cookie, err := extract(r)
+		if err != nil {
+			return nil, err
+		}
+		token, err := verify(client, r.Context(), cookie)
+		if err != nil {
+			return nil, verifyError(err)
+		}
+		return token, nil
+	}
+}
@@ -0,0 +1,67 @@
+package fauth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+// emulatorSessionCookie returns an unsigned session cookie for the fauthtest project, accepted by the Auth Emulator.
+func emulatorSessionCookie(uid string) string {
+	now := time.Now().Unix()
+	enc := base64.RawURLEncoding
+	payload := fmt.Sprintf(`{"aud":%q,"iss":"https://session.firebase.google.com/%s","sub":%q,"iat":%d,"exp":%d}`,
+		fauthtest.ProjectID, fauthtest.ProjectID, uid, now, now+3600)
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(payload)) + "."
+}
+
+func TestVerifySessionCookie(t *testing.T) {
+	fakeEmulator(t, map[string]string{"accounts:lookup": `{"users":[{"localId":"uid","validSince":"0"}]}`})
+
+	for _, onAuth := range []fauth.Option{
+		func(e *fauth.Engine) { e.OnAuth = fauth.VerifySessionCookie("__session") },
+		func(e *fauth.Engine) { e.OnAuth = fauth.VerifySessionCookieAndCheckRevoked("__session") },
+	} {
+		withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) { e.NewApp = fauthtest.NewApp }, onAuth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var uid string
+		h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+			token, _ := fauth.AuthToken(r.Context())
+			uid = token.UID
+		})
+
+		tests := []struct {
+			cookie *http.Cookie
+			err    string
+		}{
+			{&http.Cookie{Name: "__session", Value: emulatorSessionCookie("uid")}, ""},
+			{&http.Cookie{Name: "session", Value: emulatorSessionCookie("uid")}, "no_token"},
+			{&http.Cookie{Name: "__session", Value: "invalid"}, "invalid_token"},
+			{nil, "no_token"},
+		}
+		for i, tt := range tests {
+			uid = ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			h.ServeHTTP(w, r)
+			if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+				t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+			}
+			if tt.err == "" && uid != "uid" {
+				t.Fatalf("%d: invalid uid: %q", i, uid)
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package fauth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SkipPaths returns an `Engine.Skip` func skipping the verification of the requests to the given paths,
+// e.g. `/healthz` or `/metrics`. Like the patterns of `http.ServeMux`, the paths ending with a slash match
+// the whole subtree, e.g. `/public/` matches `/public/logo.png`; the other ones match exactly.
+func SkipPaths(paths ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, p := range paths {
+			if r.URL.Path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// skip reports whether the verification of the request is skipped, see `Engine.Skip` and `Engine.SkipPreflight`.
+func (e *Engine) skip(r *http.Request) bool {
+	if e.SkipPreflight && r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		return true
+	}
+	return e.Skip != nil && e.Skip(r)
+}
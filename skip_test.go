@@ -0,0 +1,85 @@
+package fauth_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestSkip(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.Skip = fauth.SkipPaths("/metrics", "/public/")
+		e.SkipPreflight = true
+	})
+	var (
+		called bool
+		data   any
+	)
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		called, data = true, fauth.AuthData(r.Context())
+	})
+	tests := []struct {
+		method    string
+		path      string
+		preflight bool
+		jwt       string
+		code      int
+		authed    bool
+	}{
+		{http.MethodGet, "/metrics", false, "", http.StatusOK, false},
+		{http.MethodGet, "/metrics/more", false, "", http.StatusUnauthorized, false},
+		{http.MethodGet, "/public/logo.png", false, "", http.StatusOK, false},
+		{http.MethodGet, "/public", false, "", http.StatusUnauthorized, false},
+		{http.MethodGet, "/metrics", false, sign(map[string]any{"sub": "uid"}), http.StatusOK, false},
+		{http.MethodOptions, "/api", true, "", http.StatusOK, false},
+		{http.MethodOptions, "/api", false, "", http.StatusUnauthorized, false},
+		{http.MethodGet, "/api", false, sign(map[string]any{"sub": "uid"}), http.StatusOK, true},
+	}
+	for i, tt := range tests {
+		called, data = false, nil
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tt.method, "http://www.example.com"+tt.path, nil)
+		if tt.preflight {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		if tt.jwt != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.jwt)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if called != (tt.code == http.StatusOK) || (data != nil) != tt.authed {
+			t.Fatalf("%d: unexpected call: %t, %v", i, called, data)
+		}
+	}
+}
+
+func TestSkipScope(t *testing.T) {
+	frozen := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+	withFirebaseAuth, _ := offlineAuth(t, func(e *fauth.Engine) {
+		e.Skip = fauth.SkipPaths("/public/")
+		e.Now = func() time.Time { return frozen }
+		e.TrustedProxies = []net.IPNet{*trusted}
+	})
+	var (
+		now time.Time
+		ip  net.IP
+	)
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		now = fauth.Now(r.Context())
+		ip, _ = fauth.ClientIP(r)
+	})
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/public/logo.png", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if !now.Equal(frozen) || !ip.Equal(net.ParseIP("203.0.113.7")) {
+		t.Fatalf("the skipped handler should follow the engine: %s, %s", now, ip)
+	}
+}
@@ -0,0 +1,60 @@
+package fauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// SSEFunc streams the server-sent events of the verified user, until the request context is done.
+// The lastEventID is the ID of the last event received by a reconnecting client, if any.
+type SSEFunc func(w http.ResponseWriter, r *http.Request, token *auth.Token, lastEventID string)
+
+// SSEHandler returns a handler streaming server-sent events to the verified users, reading the ID token from
+// the query parameter with the given name, e.g. `access_token`:
+//
+//	events, err := fauth.SSEHandler(ctx, "access_token", func(w http.ResponseWriter, r *http.Request, token *auth.Token, lastEventID string) {
+//		for event := range feed.Since(r.Context(), token.UID, lastEventID) {
+//			fmt.Fprintf(w, "id: %s\ndata: %s\n\n", event.ID, event.Data)
+//			w.(http.Flusher).Flush()
+//		}
+//	})
+//
+// Browsers can't set the headers of the `EventSource` connections, so the token has to travel in the URL, e.g.
+// `new EventSource("/events?access_token=" + idToken)`, read using `FromQuery`; mind its security caveats.
+//
+// The token is verified on every connection, including the reconnections `EventSource` makes with
+// the `Last-Event-ID` header; failures are passed to the `Engine.OnErr` func before the streaming starts.
+// Once verified, the response headers are sent and the stream runs until the client disconnects or the token expires,
+// whichever comes first, letting the client reconnect with a fresh token.
+// The options can override the query extractor by setting the `Engine.TokenExtractor`, e.g. to `FromCookie`.
+func SSEHandler(ctx context.Context, param string, stream SSEFunc, opts ...Option) (http.HandlerFunc, error) {
+	opts = append([]Option{func(e *Engine) { e.TokenExtractor = FromQuery(param) }}, opts...)
+	a, err := NewAuthenticator(ctx, append([]Option{WithoutClose("SSEHandler")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return a.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := AuthToken(r.Context())
+		if !ok || token == nil {
+			fail(w, r, errNoAuthToken)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			fail(w, r, withStatus(http.StatusInternalServerError, errors.New("fauth: streaming unsupported")))
+			return
+		}
+		ctx, cancel := ContextUntilExpiry(r.Context(), token)
+		defer cancel()
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		stream(w, r.WithContext(ctx), token, r.Header.Get("Last-Event-ID"))
+	}), nil
+}
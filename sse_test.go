@@ -0,0 +1,67 @@
+package fauth_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestSSEHandler(t *testing.T) {
+	_, sign := offlineAuth(t)
+	events, err := fauth.SSEHandler(context.Background(), "access_token",
+		func(w http.ResponseWriter, r *http.Request, token *auth.Token, lastEventID string) {
+			fmt.Fprintf(w, "data: %s %s\n\n", token.UID, lastEventID)
+		}, fauthtest.NewVerifier(&testKey.PublicKey).Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(events)
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		query       string
+		lastEventID string
+		code        int
+		data        string
+	}{
+		{"?access_token=" + sign(map[string]any{"sub": "uid"}), "", http.StatusOK, "data: uid "},
+		{"?access_token=" + sign(map[string]any{"sub": "uid"}), "42", http.StatusOK, "data: uid 42"},
+		{"?access_token=garbage", "42", http.StatusUnauthorized, ""},
+		{"", "", http.StatusUnauthorized, ""},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.query, nil)
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, resp.StatusCode)
+		}
+		if tt.code != http.StatusOK {
+			if ct := resp.Header.Get("Content-Type"); ct == "text/event-stream" {
+				t.Fatalf("%d: failures shouldn't start streaming", i)
+			}
+			resp.Body.Close()
+			continue
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("%d: invalid content type: %s", i, ct)
+		}
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		resp.Body.Close()
+		if line != tt.data+"\n" {
+			t.Fatalf("%d: expected %q, got %q", i, tt.data, line)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package fauth
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// stats holds the counters published by `PublishStats`.
+type stats struct {
+	vars     *expvar.Map
+	failures *expvar.Map
+}
+
+var (
+	statsMu       sync.Mutex
+	statsByPrefix = map[string]*stats{}
+)
+
+// PublishStats returns an Option publishing the stats of the middleware through `expvar`, under the prefix,
+// e.g. at `/debug/vars` once `expvar` is imported by a server using the `http.DefaultServeMux`:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, fauth.PublishStats("fauth"))
+//
+// The published map holds the `successes` and the `failures` by reason, i.e. by `ErrorCode`, `other` for the errors
+// without a code, along with the metrics reported to `Engine.OnMetric`, e.g. `token_cache_hit` and `token_cache_miss`,
+// and the `token_cache_hit_rate` and `user_cache_hit_rate`. The requests let through by `Engine.Optional` count as successes.
+//
+// Nothing is published unless asked to. As `expvar` variables are global, the Engines publishing under the same prefix
+// share their stats, and PublishStats panics if the prefix is already taken by a variable it didn't publish.
+func PublishStats(prefix string) Option {
+	s := publishedStats(prefix)
+	return func(e *Engine) {
+		e.stats = s
+	}
+}
+
+func publishedStats(prefix string) *stats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if s, ok := statsByPrefix[prefix]; ok {
+		return s
+	}
+	if expvar.Get(prefix) != nil {
+		panic(fmt.Sprintf("fauth: expvar %s is already published", prefix))
+	}
+	s := &stats{vars: new(expvar.Map), failures: new(expvar.Map)}
+	s.vars.Set("successes", new(expvar.Int))
+	s.vars.Set("failures", s.failures)
+	s.vars.Set("token_cache_hit_rate", expvar.Func(func() any { return s.hitRate("token_cache") }))
+	s.vars.Set("user_cache_hit_rate", expvar.Func(func() any { return s.hitRate("user_cache") }))
+	expvar.Publish(prefix, s.vars)
+	statsByPrefix[prefix] = s
+	return s
+}
+
+// record counts the outcome of a verification.
+func (s *stats) record(err error) {
+	if s == nil {
+		return
+	}
+	if err == nil {
+		s.vars.Add("successes", 1)
+		return
+	}
+	reason := ErrorCode(err)
+	if reason == "" {
+		reason = "other"
+	}
+	s.failures.Add(reason, 1)
+}
+
+// add counts a metric reported to `Engine.OnMetric`.
+func (s *stats) add(name string, value float64) {
+	if s != nil {
+		s.vars.AddFloat(name, value)
+	}
+}
+
+// hitRate returns the ratio of the hits of the cache, 0 if it was never queried.
+func (s *stats) hitRate(cache string) float64 {
+	hits, misses := s.float(cache+"_hit"), s.float(cache+"_miss")
+	if hits+misses == 0 {
+		return 0
+	}
+	return hits / (hits + misses)
+}
+
+func (s *stats) float(name string) float64 {
+	if f, ok := s.vars.Get(name).(*expvar.Float); ok {
+		return f.Value()
+	}
+	return 0
+}
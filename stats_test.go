@@ -0,0 +1,43 @@
+package fauth_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestPublishStats(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, fauth.PublishStats("fauth_test"))
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	serveBearer(h, sign(map[string]any{"sub": "uid"}))
+	serveBearer(h, sign(map[string]any{"sub": "uid"}))
+	serveBearer(h, "invalid")
+	serveBearer(h, sign(map[string]any{"sub": "uid", "exp": time.Now().Add(-time.Minute).Unix()}))
+
+	// Engines publishing under the same prefix share their stats.
+	withSameStats, sign := offlineAuth(t, fauth.PublishStats("fauth_test"))
+	serveBearer(withSameStats(func(w http.ResponseWriter, r *http.Request) {}), sign(map[string]any{"sub": "uid"}))
+
+	var stats struct {
+		Successes int            `json:"successes"`
+		Failures  map[string]int `json:"failures"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("fauth_test").String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Successes != 3 || stats.Failures["invalid_token"] != 1 || stats.Failures["expired"] != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	expvar.NewInt("fauth_taken")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("taken prefixes should panic")
+		}
+	}()
+	fauth.PublishStats("fauth_taken")
+}
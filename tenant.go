@@ -0,0 +1,82 @@
+package fauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrNoTenant is returned when the tenant of the request can't be resolved.
+var ErrNoTenant = errors.New("fauth: no tenant")
+
+const tenantContextKey contextKey = "tenant"
+
+// Tenant returns the ID of the tenant the request was verified against, resolved by the `Engine.TenantID` func.
+func Tenant(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey).(string)
+	return tenant, ok && tenant != ""
+}
+
+// resolveTenant stores the tenant of the request, resolved by the `Engine.TenantID` func if set, in its context.
+func (e *Engine) resolveTenant(r *http.Request) (*http.Request, error) {
+	if e.TenantID == nil {
+		return r, nil
+	}
+	tenant, err := e.TenantID(r)
+	if err != nil {
+		return r, err
+	}
+	if tenant == "" {
+		return r, ErrNoTenant
+	}
+	return r.WithContext(context.WithValue(r.Context(), tenantContextKey, tenant)), nil
+}
+
+// checkTenant makes sure the verified token was issued by the tenant of the request, if any,
+// whichever verifier the `Engine.OnAuth` func uses.
+func (e *Engine) checkTenant(r *http.Request, data any) error {
+	tenant, ok := Tenant(r.Context())
+	if !ok {
+		return nil
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return nil
+	}
+	if token.Firebase.Tenant != tenant {
+		return fmt.Errorf("%w: expected tenant %s, got %q", ErrInvalidToken, tenant, token.Firebase.Tenant)
+	}
+	return nil
+}
+
+// TenantFromSubdomain returns a func resolving the tenant ID of the request from the leftmost label
+// of its host under the base domain, e.g. `acme` for `acme.app.com` with the `app.com` base domain.
+//
+// The apex domain and its `www` subdomain don't carry a tenant, so they're rejected with `ErrNoTenant`,
+// along with the hosts outside of the base domain. A leading `www` label is skipped, so `www.acme.app.com`
+// resolves to `acme`, while deeper subdomains resolve to their leftmost label, e.g. `eu.acme.app.com` to `eu`.
+func TenantFromSubdomain(baseDomain string) func(r *http.Request) (string, error) {
+	base := "." + strings.Trim(strings.ToLower(baseDomain), ".")
+	return func(r *http.Request) (string, error) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.ToLower(host), ".")
+		sub, ok := strings.CutSuffix(host, base)
+		if !ok || sub == "" {
+			return "", fmt.Errorf("%w: %s", ErrNoTenant, r.Host)
+		}
+		labels := strings.Split(sub, ".")
+		if labels[0] == "www" {
+			labels = labels[1:]
+		}
+		if len(labels) == 0 || labels[0] == "" {
+			return "", fmt.Errorf("%w: %s", ErrNoTenant, r.Host)
+		}
+		return labels[0], nil
+	}
+}
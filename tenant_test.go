@@ -0,0 +1,151 @@
+package fauth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestTenantFromSubdomain(t *testing.T) {
+	resolve := fauth.TenantFromSubdomain("app.com")
+	tests := []struct {
+		host   string
+		tenant string
+	}{
+		{"acme.app.com", "acme"},
+		{"ACME.App.com", "acme"},
+		{"acme.app.com:8080", "acme"},
+		{"acme.app.com.", "acme"},
+		{"www.acme.app.com", "acme"},
+		{"eu.acme.app.com", "eu"},
+		{"app.com", ""},
+		{"www.app.com", ""},
+		{"app.com:8080", ""},
+		{"acme.other.com", ""},
+		{"acmeapp.com", ""},
+		{".app.com", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Host = tt.host
+		tenant, err := resolve(r)
+		if tt.tenant == "" {
+			if !errors.Is(err, fauth.ErrNoTenant) {
+				t.Fatalf("%s: expected ErrNoTenant, got: %v", tt.host, err)
+			}
+			continue
+		}
+		if err != nil || tenant != tt.tenant {
+			t.Fatalf("%s: expected %s, got %s, %v", tt.host, tt.tenant, tenant, err)
+		}
+	}
+}
+
+// emulatorTenantToken returns an unsigned token issued by the tenant, accepted by the Auth Emulator.
+func emulatorTenantToken(uid, tenant string) string {
+	now := time.Now().Unix()
+	enc := base64.RawURLEncoding
+	payload := fmt.Sprintf(`{"aud":%q,"iss":"https://securetoken.google.com/%s","sub":%q,"iat":%d,"exp":%d,"firebase":{"tenant":%q}}`,
+		fauthtest.ProjectID, fauthtest.ProjectID, uid, now, now+3600, tenant)
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(payload)) + "."
+}
+
+func TestTenantID(t *testing.T) {
+	fakeEmulator(t, map[string]string{"accounts:lookup": `{"users":[{"localId":"uid","validSince":"0"}]}`})
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.TenantID = func(r *http.Request) (string, error) {
+			if tenant := r.Header.Get("X-Tenant"); tenant != "" {
+				return tenant, nil
+			}
+			return "", fauth.ErrNoTenant
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tenant string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		tenant, _ = fauth.Tenant(r.Context())
+	})
+	tests := []struct {
+		header string
+		jwt    string
+		code   int
+		err    string
+	}{
+		{"acme", emulatorTenantToken("uid", "acme"), http.StatusOK, ""},
+		{"acme", emulatorTenantToken("uid", "other"), http.StatusUnauthorized, "invalid_token"},
+		{"acme", fauthtest.EmulatorToken("uid"), http.StatusUnauthorized, "invalid_token"},
+		{"", emulatorTenantToken("uid", "acme"), http.StatusUnauthorized, "no_tenant"},
+	}
+	for i, tt := range tests {
+		tenant = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("", "http://www.example.com", nil)
+		r.Header.Set("Authorization", "Bearer "+tt.jwt)
+		r.Header.Set("X-Tenant", tt.header)
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+		if tt.code == http.StatusOK && tenant != tt.header {
+			t.Fatalf("%d: expected tenant %s, got %s", i, tt.header, tenant)
+		}
+	}
+}
+
+func TestTenantUser(t *testing.T) {
+	fakeEmulator(t, map[string]string{
+		"tenants/acme/accounts:lookup":  `{"users":[{"localId":"uid","email":"uid@acme.com"}]}`,
+		"tenants/other/accounts:lookup": `{"users":[{"localId":"uid","email":"uid@other.com"}]}`,
+	})
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = fauth.VerifyIDTokenWithUser
+		e.UserCacheTTL = time.Minute
+		e.TenantID = func(r *http.Request) (string, error) {
+			return r.Header.Get("X-Tenant"), nil
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var email string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := fauth.User(r.Context())
+		email = user.Email
+	})
+	// The second round is served from the cache, which must keep the users of the tenants apart.
+	for i := 0; i < 2; i++ {
+		for _, tenant := range []string{"acme", "other"} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("", "http://www.example.com", nil)
+			r.Header.Set("Authorization", "Bearer "+emulatorTenantToken("uid", tenant))
+			r.Header.Set("X-Tenant", tenant)
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusOK || email != "uid@"+tenant+".com" {
+				t.Fatalf("%s: the user should be fetched from the tenant, got: %d, %s", tenant, w.Code, email)
+			}
+		}
+	}
+
+	_, err = fauth.LoginHandler(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.TenantID = fauth.TenantFromSubdomain("app.com")
+	})
+	if err == nil {
+		t.Fatal("LoginHandler should reject the TenantID")
+	}
+}
@@ -0,0 +1,141 @@
+package fauth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// ContextUntilExpiry returns a copy of the context canceled when the token expires, tying the lifetime
+// of the work started on behalf of the user, e.g. background jobs kicked off by a request, to their credential.
+// As with `context.WithDeadline`, callers must call the cancel func once the work is done to release its resources.
+//
+//	ctx, cancel := fauth.ContextUntilExpiry(context.Background(), token)
+//	go func() {
+//		defer cancel()
+//		export(ctx, token.UID)
+//	}()
+func ContextUntilExpiry(ctx context.Context, token *auth.Token) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(ctx, time.Unix(token.Expires, 0))
+}
+
+// SignInTime returns the time the user signed in, i.e. the `auth_time` claim of the verified token.
+// Unlike the issue time of the token, it doesn't change when the token is refreshed, which makes it
+// suitable for step-up and risk decisions. It returns false when the claim is absent.
+func SignInTime(ctx context.Context) (time.Time, bool) {
+	token, ok := AuthToken(ctx)
+	if !ok || token == nil {
+		return time.Time{}, false
+	}
+	if token.AuthTime != 0 {
+		return time.Unix(token.AuthTime, 0), true
+	}
+	if t, ok := token.Claims["auth_time"].(float64); ok {
+		return time.Unix(int64(t), 0), true
+	}
+	return time.Time{}, false
+}
+
+// IsNewSession reports whether the user signed in within the window, according to `SignInTime`,
+// e.g. to run the onboarding on the first requests after the login:
+//
+//	if isNew, _ := fauth.IsNewSession(r.Context(), time.Minute); isNew {
+//		onboard(r.Context())
+//	}
+//
+// The current time is read from the `Engine.Now` clock. It returns false when the `auth_time` claim is absent.
+func IsNewSession(ctx context.Context, window time.Duration) (isNew bool, ok bool) {
+	signIn, ok := SignInTime(ctx)
+	if !ok {
+		return false, false
+	}
+	return Now(ctx).Sub(signIn) <= window, true
+}
+
+// Identity returns the identifiers of the user with the given provider, read from the `firebase.identities` claim
+// of the verified token, e.g. the Google `sub` for `google.com` or the phone number for `phone`.
+// It's meant for account linking and provider-specific logic. It returns false when the provider isn't present.
+func Identity(ctx context.Context, provider string) ([]string, bool) {
+	token, ok := AuthToken(ctx)
+	if !ok || token == nil {
+		return nil, false
+	}
+	identities := token.Firebase.Identities
+	if identities == nil {
+		v, _ := Claims(token.Claims).Get("firebase.identities")
+		identities, _ = v.(map[string]any)
+	}
+	v, ok := identities[provider].([]any)
+	if !ok {
+		return nil, false
+	}
+	ids := make([]string, 0, len(v))
+	for _, id := range v {
+		if s, ok := id.(string); ok {
+			ids = append(ids, s)
+		}
+	}
+	return ids, true
+}
+
+// Email returns the `email` claim of the token. It returns false when the token is nil
+// or the claim is absent, empty or not a string, e.g. for users signed in with their phone number.
+func Email(token *auth.Token) (string, bool) {
+	return stringClaim(token, "email")
+}
+
+// EmailVerified reports whether the `email_verified` claim of the token is true.
+// It returns false when the token is nil or the claim is absent or not a bool.
+func EmailVerified(token *auth.Token) bool {
+	if token == nil {
+		return false
+	}
+	verified, _ := token.Claims["email_verified"].(bool)
+	return verified
+}
+
+// Name returns the `name` claim of the token, i.e. the display name of the user. It returns false
+// when the token is nil or the claim is absent, empty or not a string.
+func Name(token *auth.Token) (string, bool) {
+	return stringClaim(token, "name")
+}
+
+// SignInProvider returns the provider the user signed in with, e.g. `google.com`, `password` or `anonymous`,
+// read from the `firebase.sign_in_provider` claim of the token. It returns false when the token is nil
+// or the claim is absent.
+func SignInProvider(token *auth.Token) (string, bool) {
+	if token == nil {
+		return "", false
+	}
+	if p := token.Firebase.SignInProvider; p != "" {
+		return p, true
+	}
+	p, _ := Claims(token.Claims).String("firebase.sign_in_provider")
+	return p, p != ""
+}
+
+func stringClaim(token *auth.Token, key string) (string, bool) {
+	if token == nil {
+		return "", false
+	}
+	s, _ := token.Claims[key].(string)
+	return s, s != ""
+}
+
+// checkLifetime makes sure the lifetime the verified token claims, i.e. its `exp` minus its `iat`,
+// doesn't exceed the `Engine.MaxTokenLifetime`, if set.
+func (e *Engine) checkLifetime(data any) error {
+	if e.MaxTokenLifetime <= 0 {
+		return nil
+	}
+	token, ok := tokenOf(data)
+	if !ok || token == nil {
+		return nil
+	}
+	if lifetime := time.Duration(token.Expires-token.IssuedAt) * time.Second; lifetime > e.MaxTokenLifetime {
+		return fmt.Errorf("%w: lifetime of %s exceeds %s", ErrInvalidToken, lifetime, e.MaxTokenLifetime)
+	}
+	return nil
+}
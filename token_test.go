@@ -0,0 +1,176 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+)
+
+func TestSignInTime(t *testing.T) {
+	signIn := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		token *auth.Token
+		ok    bool
+	}{
+		{nil, false},
+		{&auth.Token{IssuedAt: signIn.Add(time.Hour).Unix()}, false},
+		{&auth.Token{AuthTime: signIn.Unix()}, true},
+		{&auth.Token{Claims: map[string]any{"auth_time": float64(signIn.Unix())}}, true},
+	}
+	for i, tt := range tests {
+		ctx := context.Background()
+		if tt.token != nil {
+			ctx = fauth.WithAuthData(ctx, tt.token)
+		}
+		st, ok := fauth.SignInTime(ctx)
+		if ok != tt.ok {
+			t.Fatalf("%d: expected %v, got %v", i, tt.ok, ok)
+		}
+		if ok && !st.Equal(signIn) {
+			t.Fatalf("%d: invalid sign-in time: %v", i, st)
+		}
+	}
+}
+
+func TestIsNewSession(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		token *auth.Token
+		isNew bool
+		ok    bool
+	}{
+		{nil, false, false},
+		{&auth.Token{IssuedAt: now.Unix()}, false, false},
+		{&auth.Token{AuthTime: now.Add(-30 * time.Second).Unix()}, true, true},
+		{&auth.Token{AuthTime: now.Add(-time.Hour).Unix()}, false, true},
+		{&auth.Token{Claims: map[string]any{"auth_time": float64(now.Unix())}}, true, true},
+	}
+	for i, tt := range tests {
+		ctx := context.Background()
+		if tt.token != nil {
+			ctx = fauth.WithAuthData(ctx, tt.token)
+		}
+		if isNew, ok := fauth.IsNewSession(ctx, time.Minute); isNew != tt.isNew || ok != tt.ok {
+			t.Fatalf("%d: expected %v, %v, got %v, %v", i, tt.isNew, tt.ok, isNew, ok)
+		}
+	}
+}
+
+func TestContextUntilExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	ctx, cancel := fauth.ContextUntilExpiry(context.Background(), &auth.Token{Expires: exp})
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || d.Unix() != exp {
+		t.Fatalf("invalid deadline: %v", d)
+	}
+
+	ctx, cancel = fauth.ContextUntilExpiry(context.Background(), &auth.Token{Expires: time.Now().Add(-time.Minute).Unix()})
+	defer cancel()
+	if ctx.Err() == nil {
+		t.Fatal("the context of an expired token should be done")
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	identities := map[string]any{
+		"google.com": []any{"1234567890"},
+		"phone":      []any{"+15555550100"},
+	}
+	tokens := []*auth.Token{
+		{Firebase: auth.FirebaseInfo{Identities: identities}},
+		{Claims: map[string]any{"firebase": map[string]any{"identities": identities}}},
+	}
+	for i, token := range tokens {
+		ctx := fauth.WithAuthData(context.Background(), token)
+		if ids, ok := fauth.Identity(ctx, "google.com"); !ok || len(ids) != 1 || ids[0] != "1234567890" {
+			t.Fatalf("%d: invalid google.com identity: %v", i, ids)
+		}
+		if ids, ok := fauth.Identity(ctx, "phone"); !ok || len(ids) != 1 || ids[0] != "+15555550100" {
+			t.Fatalf("%d: invalid phone identity: %v", i, ids)
+		}
+		if _, ok := fauth.Identity(ctx, "github.com"); ok {
+			t.Fatalf("%d: github.com shouldn't be present", i)
+		}
+	}
+	if _, ok := fauth.Identity(fauth.WithAuthData(context.Background(), &auth.Token{}), "phone"); ok {
+		t.Fatal("phone shouldn't be present")
+	}
+	if _, ok := fauth.Identity(context.Background(), "phone"); ok {
+		t.Fatal("phone shouldn't be present without a token")
+	}
+}
+
+func TestClaimAccessors(t *testing.T) {
+	token := &auth.Token{
+		Firebase: auth.FirebaseInfo{SignInProvider: "google.com"},
+		Claims: map[string]any{
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"name":           "Alice",
+		},
+	}
+	if email, ok := fauth.Email(token); !ok || email != "alice@example.com" {
+		t.Fatalf("invalid email: %s, %v", email, ok)
+	}
+	if !fauth.EmailVerified(token) {
+		t.Fatal("the email should be verified")
+	}
+	if name, ok := fauth.Name(token); !ok || name != "Alice" {
+		t.Fatalf("invalid name: %s, %v", name, ok)
+	}
+	if p, ok := fauth.SignInProvider(token); !ok || p != "google.com" {
+		t.Fatalf("invalid provider: %s, %v", p, ok)
+	}
+
+	claims := &auth.Token{Claims: map[string]any{"firebase": map[string]any{"sign_in_provider": "phone"}}}
+	if p, ok := fauth.SignInProvider(claims); !ok || p != "phone" {
+		t.Fatalf("invalid provider from the claims: %s, %v", p, ok)
+	}
+
+	wrong := &auth.Token{Claims: map[string]any{"email": 42, "email_verified": "true", "name": []any{"Alice"}}}
+	for _, token := range []*auth.Token{nil, {}, wrong} {
+		if _, ok := fauth.Email(token); ok {
+			t.Fatalf("%v: unexpected email", token)
+		}
+		if fauth.EmailVerified(token) {
+			t.Fatalf("%v: the email shouldn't be verified", token)
+		}
+		if _, ok := fauth.Name(token); ok {
+			t.Fatalf("%v: unexpected name", token)
+		}
+		if _, ok := fauth.SignInProvider(token); ok {
+			t.Fatalf("%v: unexpected provider", token)
+		}
+	}
+}
+
+func TestMaxTokenLifetime(t *testing.T) {
+	withFirebaseAuth, sign := offlineAuth(t, func(e *fauth.Engine) {
+		e.MaxTokenLifetime = time.Hour
+	})
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	now := time.Now()
+	tests := []struct {
+		iat, exp time.Time
+		code     int
+	}{
+		{now, now.Add(time.Hour), http.StatusOK},
+		{now.Add(-time.Minute), now.Add(59 * time.Minute), http.StatusOK},
+		{now, now.Add(time.Hour + time.Second), http.StatusUnauthorized},
+		{now.Add(-30 * 24 * time.Hour), now.Add(time.Hour), http.StatusUnauthorized},
+		{time.Unix(0, 0), now.Add(time.Hour), http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		w := serveBearer(h, sign(map[string]any{"sub": "uid", "iat": tt.iat.Unix(), "exp": tt.exp.Unix()}))
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if tt.code != http.StatusOK && w.Header().Get("X-Auth-Error") != "invalid_token" {
+			t.Fatalf("%d: invalid error: %q", i, w.Header().Get("X-Auth-Error"))
+		}
+	}
+}
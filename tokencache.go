@@ -0,0 +1,126 @@
+package fauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/errorutils"
+)
+
+const defaultTokenCacheSize = 1024
+
+// cachedToken is a verified token, served from the cache until it's stale,
+// and until it expires if the verification fails transiently, see `Engine.ServeStaleTokens`.
+type cachedToken struct {
+	token *auth.Token
+	stale time.Time
+}
+
+// verifyIDToken verifies the ID token, checking whether it has been revoked if asked to, against the tenant
+// of the request if any, see `Engine.TenantID`, using the token cache of the request scope if any, see `Engine.TokenCacheTTL`.
+func verifyIDToken(ctx context.Context, client *auth.Client, jwt string, checkRevoked bool) (*auth.Token, error) {
+	s := scopeFrom(ctx)
+	t := traceFrom(ctx)
+	if t == nil {
+		t = &VerifyTrace{}
+	}
+	t.CheckRevoked = checkRevoked
+	tenant, _ := Tenant(ctx)
+	// The tokens verified without the revocation check, or for another tenant, mustn't be served from the cache.
+	key := tenant + ":" + jwt
+	if checkRevoked {
+		key = "revoked:" + key
+	}
+	var (
+		cached   cachedToken
+		isCached bool
+	)
+	if s.tokens != nil {
+		if cached, isCached = s.tokens.get(key); isCached && s.engine.Now().Before(cached.stale) {
+			s.engine.metric("token_cache_hit", 1)
+			t.Cache = "hit"
+			return copyToken(cached.token), nil
+		}
+		s.engine.metric("token_cache_miss", 1)
+		t.Cache = "miss"
+	}
+	verify, err := verifier(s.engine.Verifier, client, tenant, checkRevoked)
+	if err != nil {
+		return nil, err
+	}
+	token, err := verify(ctx, jwt)
+	if err != nil {
+		if s.tokens == nil || !s.engine.ServeStaleTokens || !transient(err) {
+			return nil, verifyError(err)
+		}
+		if isCached {
+			s.engine.metric("token_cache_stale_hit", 1)
+			t.Cache = "stale_hit"
+			return copyToken(cached.token), nil
+		}
+		return nil, unavailable(fmt.Errorf("%w: %w", ErrVerifierUnavailable, err))
+	}
+	if s.tokens != nil {
+		exp := time.Unix(token.Expires, 0)
+		stale := s.engine.Now().Add(s.engine.TokenCacheTTL)
+		if exp.Before(stale) {
+			stale = exp
+		}
+		expires := stale
+		if s.engine.ServeStaleTokens {
+			// The stale tokens are kept around until they expire, in case the verifier becomes unavailable.
+			expires = exp
+		}
+		s.tokens.add(key, cachedToken{token: copyToken(token), stale: stale}, expires)
+	}
+	return token, nil
+}
+
+// transient reports whether the verification failed without telling anything about the token, e.g. because
+// the public keys couldn't be fetched, or the revocation check RPC failed or timed out.
+func transient(err error) bool {
+	return errorutils.IsUnavailable(err) || errorutils.IsDeadlineExceeded(err) || errorutils.IsInternal(err) ||
+		errorutils.IsUnknown(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Verifier verifies the Firebase ID tokens. It's satisfied by the `*auth.Client` and the `*auth.TenantClient`,
+// and by fakes in tests, see `Engine.Verifier`.
+type Verifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
+	VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+// verifier returns the func verifying the ID tokens using the `Engine.Verifier`, if set,
+// or the auth client of the tenant, or of the project if there's none.
+func verifier(v Verifier, client *auth.Client, tenant string, checkRevoked bool) (func(ctx context.Context, jwt string) (*auth.Token, error), error) {
+	if v == nil && tenant == "" {
+		v = client
+	}
+	if v == nil {
+		tc, err := client.TenantManager.AuthForTenant(tenant)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNoTenant, err)
+		}
+		v = tc
+	}
+	if checkRevoked {
+		return v.VerifyIDTokenAndCheckRevoked, nil
+	}
+	return v.VerifyIDToken, nil
+}
+
+// copyToken returns a copy of the token and its claims, so the ones served from the cache
+// can be altered by the request handling, e.g. by the `Engine.ClaimNamespace` normalization.
+func copyToken(token *auth.Token) *auth.Token {
+	t := *token
+	if token.Claims != nil {
+		t.Claims = make(map[string]any, len(token.Claims))
+		for k, v := range token.Claims {
+			t.Claims[k] = v
+		}
+	}
+	return &t
+}
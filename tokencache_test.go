@@ -0,0 +1,131 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestTokenCache(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		_, _ = w.Write([]byte(`{"users":[{"localId":"uid","validSince":"0"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	now := time.Now()
+	metrics := map[string]float64{}
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked
+		e.TokenCacheTTL = 2 * time.Hour
+		e.Now = func() time.Time { return now }
+		e.OnMetric = func(name string, value float64) { metrics[name] += value }
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := fauth.AuthToken(r.Context())
+		token.Claims["touched"] = true
+	})
+
+	jwt := fauthtest.EmulatorToken("uid")
+	tests := []struct {
+		jwt     string
+		advance time.Duration
+		lookups int32
+	}{
+		{jwt, 0, 1},
+		{jwt, 0, 1},
+		{jwt, 30 * time.Minute, 1},
+		{fauthtest.EmulatorToken("other"), 0, 2},
+		// The token expires before the TTL.
+		{jwt, 31 * time.Minute, 3},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		if w := serveBearer(h, tt.jwt); w.Code != http.StatusOK {
+			t.Fatalf("%d: invalid status: %d", i, w.Code)
+		}
+		if n := lookups.Load(); n != tt.lookups {
+			t.Fatalf("%d: expected %d lookups, got %d", i, tt.lookups, n)
+		}
+	}
+	if metrics["token_cache_hit"] != 2 || metrics["token_cache_miss"] != 3 {
+		t.Fatalf("invalid metrics: %v", metrics)
+	}
+
+	if w := serveBearer(h, "invalid"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestServeStaleTokens(t *testing.T) {
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			// Unlike 503, not retried by the Admin SDK.
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"users":[{"localId":"uid","validSince":"0"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	now := time.Now()
+	newHandler := func(stale bool) http.HandlerFunc {
+		withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+			e.NewApp = fauthtest.NewApp
+			e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked
+			e.TokenCacheTTL = time.Minute
+			e.ServeStaleTokens = stale
+			e.Now = func() time.Time { return now }
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+	}
+	h, strict := newHandler(true), newHandler(false)
+
+	jwt := fauthtest.EmulatorToken("uid")
+	tests := []struct {
+		h       http.HandlerFunc
+		jwt     string
+		down    bool
+		advance time.Duration
+		code    int
+		err     string
+	}{
+		{h, jwt, false, 0, http.StatusOK, ""},
+		{strict, jwt, false, 0, http.StatusOK, ""},
+		{h, jwt, true, 2 * time.Minute, http.StatusOK, ""},
+		{strict, jwt, true, 0, http.StatusUnauthorized, ""},
+		{h, fauthtest.EmulatorToken("other"), true, 0, http.StatusServiceUnavailable, "verifier_unavailable"},
+		{h, jwt, false, 0, http.StatusOK, ""},
+		// The stale tokens aren't served past their expiry.
+		{h, jwt, true, time.Hour, http.StatusServiceUnavailable, "verifier_unavailable"},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		down.Store(tt.down)
+		w := serveBearer(tt.h, tt.jwt)
+		if w.Code != tt.code {
+			t.Fatalf("%d: expected %d, got %d", i, tt.code, w.Code)
+		}
+		if e := w.Header().Get("X-Auth-Error"); e != tt.err {
+			t.Fatalf("%d: expected %q, got %q", i, tt.err, e)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package fauth
+
+import (
+	"context"
+	"net/http"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// VerifyTrace describes a verification, i.e. a run of the `Engine.OnAuth` func, to the `Engine.TraceVerify` hook.
+type VerifyTrace struct {
+	// CheckRevoked reports whether the token was checked for revocation by the built-in ID token verifiers.
+	CheckRevoked bool
+	// Cache is `hit`, `miss` or `stale_hit` when the token went through the token cache, see `Engine.TokenCacheTTL`,
+	// empty otherwise.
+	Cache string
+	// Err is the verification error, nil on success.
+	Err error
+}
+
+// Outcome returns the outcome of the verification, see `Engine.OnVerify`.
+func (t *VerifyTrace) Outcome() string {
+	return outcome(t.Err)
+}
+
+// outcome returns `ok` if the error is nil, its code otherwise, see `ErrorCode`, `network` for the transient failures
+// without a code, e.g. the public keys failing to be fetched, and `error` for the other ones.
+func outcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if code := ErrorCode(err); code != "" {
+		return code
+	}
+	if StatusCode(err) == http.StatusServiceUnavailable || causedBy(err, transient) {
+		return "network"
+	}
+	return "error"
+}
+
+// causedBy reports whether any error of the tree of the error matches, unlike `errors.Is` relying on the match func,
+// e.g. for the Firebase errors whose predicates don't unwrap them.
+func causedBy(err error, match func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if match(err) {
+		return true
+	}
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return causedBy(u.Unwrap(), match)
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			if causedBy(e, match) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const traceContextKey contextKey = "trace"
+
+// traceFrom returns the trace of the verification running in the context, if any.
+func traceFrom(ctx context.Context) *VerifyTrace {
+	t, _ := ctx.Value(traceContextKey).(*VerifyTrace)
+	return t
+}
+
+// traced runs the authentication within the `Engine.TraceVerify` hook, if set.
+func (e *Engine) traced(r *http.Request, app *firebase.App, client *auth.Client) (any, *http.Request, error) {
+	if e.TraceVerify == nil {
+		return e.authenticate(r, app, client)
+	}
+	ctx := r.Context()
+	tctx, end := e.TraceVerify(ctx)
+	t := &VerifyTrace{}
+	data, rt, err := e.authenticate(r.WithContext(context.WithValue(tctx, traceContextKey, t)), app, client)
+	t.Err = err
+	end(t)
+	return data, rt.WithContext(ctx), err
+}
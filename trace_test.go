@@ -0,0 +1,111 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+type spanKey struct{}
+
+func TestTraceVerify(t *testing.T) {
+	fakeEmulator(t, map[string]string{"accounts:lookup": `{"users":[{"localId":"uid","validSince":"0"}]}`})
+	var (
+		traces []fauth.VerifyTrace
+		inSpan bool
+	)
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked
+		e.TokenCacheTTL = time.Minute
+		e.TraceVerify = func(ctx context.Context) (context.Context, func(t *fauth.VerifyTrace)) {
+			return context.WithValue(ctx, spanKey{}, true), func(t *fauth.VerifyTrace) {
+				traces = append(traces, *t)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		inSpan = r.Context().Value(spanKey{}) != nil
+	})
+	jwt := fauthtest.EmulatorToken("uid")
+	serveBearer(h, jwt)
+	serveBearer(h, jwt)
+	serveBearer(h, "invalid")
+	if inSpan {
+		t.Fatal("the handler shouldn't run in the verification context")
+	}
+
+	expected := []struct {
+		cache   string
+		outcome string
+	}{
+		{"miss", "ok"},
+		{"hit", "ok"},
+		{"miss", "invalid_token"},
+	}
+	if len(traces) != len(expected) {
+		t.Fatalf("expected %d traces, got %d", len(expected), len(traces))
+	}
+	for i, e := range expected {
+		if tr := traces[i]; !tr.CheckRevoked || tr.Cache != e.cache || tr.Outcome() != e.outcome {
+			t.Fatalf("%d: unexpected trace: %+v, %s", i, tr, tr.Outcome())
+		}
+	}
+}
+
+func TestOnVerify(t *testing.T) {
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"users":[{"localId":"uid","validSince":"0"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	now := time.Now()
+	var outcomes []string
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = fauth.VerifyIDTokenAndCheckRevoked
+		e.Now = func() time.Time {
+			now = now.Add(time.Millisecond)
+			return now
+		}
+		e.OnVerify = func(outcome string, d time.Duration) {
+			if d != time.Millisecond {
+				t.Errorf("unexpected duration: %v", d)
+			}
+			outcomes = append(outcomes, outcome)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {})
+
+	serveBearer(h, fauthtest.EmulatorToken("uid"))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("", "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	h.ServeHTTP(w, r)
+	down.Store(true)
+	serveBearer(h, fauthtest.EmulatorToken("uid"))
+
+	if expected := []string{"ok", "malformed", "network"}; !slices.Equal(outcomes, expected) {
+		t.Fatalf("expected %v, got %v", expected, outcomes)
+	}
+}
@@ -0,0 +1,82 @@
+package fauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+const defaultUserCacheSize = 1024
+
+// VerifyIDTokenWithUser is like `VerifyIDTokenResult`, but it also fetches the user record of the token,
+// available to the handlers through the `User` func. Set the `Engine.UserCacheTTL` to cache the records,
+// skipping the `GetUser` RPC for the repeated requests of the same user:
+//
+//	withFirebaseAuth, err := fauth.Auth(ctx, func(e *fauth.Engine) {
+//		e.OnAuth = fauth.VerifyIDTokenWithUser
+//		e.UserCacheTTL = time.Minute
+//	})
+func VerifyIDTokenWithUser(r *http.Request, app *firebase.App, client *auth.Client) (any, error) {
+	res, err := verifyResult(r, client)
+	if err != nil {
+		return nil, err
+	}
+	if res.User, err = scopeFrom(r.Context()).user(r.Context(), client, res.UID()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// User returns the user record fetched by `VerifyIDTokenWithUser`.
+func User(ctx context.Context) (*auth.UserRecord, bool) {
+	res, ok := AuthResult(ctx)
+	if !ok || res.User == nil {
+		return nil, false
+	}
+	return res.User, true
+}
+
+// user returns the user record with the UID, from the cache of the scope if any. The users of the tenant
+// of the request, see `Engine.TenantID`, are fetched from its auth client and cached apart from the other ones.
+func (s *scope) user(ctx context.Context, client *auth.Client, uid string) (*auth.UserRecord, error) {
+	tenant, _ := Tenant(ctx)
+	// The tenant IDs have no colons, so the keys of the users of different tenants, or of the project, can't collide.
+	key := tenant + ":" + uid
+	if s.users != nil {
+		if user, ok := s.users.get(key); ok {
+			s.engine.metric("user_cache_hit", 1)
+			return user, nil
+		}
+		s.engine.metric("user_cache_miss", 1)
+	}
+	getUser := client.GetUser
+	if tenant != "" {
+		tc, err := client.TenantManager.AuthForTenant(tenant)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNoTenant, err)
+		}
+		getUser = tc.GetUser
+	}
+	user, err := getUser(ctx, uid)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return nil, verifyError(err)
+		}
+		return nil, unavailable(fmt.Errorf("fauth: failed to get the user: %w", err))
+	}
+	if s.users != nil {
+		s.users.add(key, user, s.engine.Now().Add(s.engine.UserCacheTTL))
+	}
+	return user, nil
+}
+
+// metric reports the metric to the `Engine.OnMetric` func, if set, and to the stats published by `PublishStats`.
+func (e *Engine) metric(name string, value float64) {
+	e.stats.add(name, value)
+	if e.OnMetric != nil {
+		e.OnMetric(name, value)
+	}
+}
@@ -0,0 +1,83 @@
+package fauth_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestVerifyIDTokenWithUser(t *testing.T) {
+	fakeEmulator(t, map[string]string{
+		"accounts:lookup": `{"users":[{"localId":"uid","email":"user@example.com","validSince":"0"}]}`,
+	})
+	metrics := map[string]float64{}
+	now := time.Now()
+	withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnAuth = fauth.VerifyIDTokenWithUser
+		e.UserCacheTTL = time.Minute
+		e.Now = func() time.Time { return now }
+		e.OnMetric = func(name string, value float64) {
+			metrics[name] += value
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var email string
+	h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := fauth.User(r.Context())
+		email = user.Email
+	})
+	for i := 0; i < 2; i++ {
+		if w := serveBearer(h, fauthtest.EmulatorToken("uid")); w.Code != http.StatusOK {
+			t.Fatalf("invalid status: %d", w.Code)
+		}
+		if email != "user@example.com" {
+			t.Fatalf("invalid email: %s", email)
+		}
+	}
+	if metrics["user_cache_miss"] != 1 || metrics["user_cache_hit"] != 1 {
+		t.Fatalf("the second request should hit the cache: %v", metrics)
+	}
+
+	now = now.Add(time.Minute)
+	serveBearer(h, fauthtest.EmulatorToken("uid"))
+	if metrics["user_cache_miss"] != 2 {
+		t.Fatalf("the cached user should expire: %v", metrics)
+	}
+}
+
+func TestRequireAccountAge(t *testing.T) {
+	for age, code := range map[time.Duration]int{
+		2 * time.Hour:    http.StatusOK,
+		30 * time.Minute: http.StatusForbidden,
+	} {
+		created := time.Now().Add(-age).UnixMilli()
+		fakeEmulator(t, map[string]string{
+			"accounts:lookup": fmt.Sprintf(`{"users":[{"localId":"uid","validSince":"0","createdAt":"%d"}]}`, created),
+		})
+		withFirebaseAuth, err := fauth.Auth(context.Background(), func(e *fauth.Engine) {
+			e.NewApp = fauthtest.NewApp
+			e.OnAuth = fauth.VerifyIDTokenWithUser
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := withFirebaseAuth(fauth.RequireAccountAge(time.Hour)(func(w http.ResponseWriter, r *http.Request) {}))
+		if w := serveBearer(h, fauthtest.EmulatorToken("uid")); w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", age, code, w.Code)
+		}
+	}
+
+	if code := serveWithToken(fauth.RequireAccountAge(time.Hour), &auth.Token{UID: "uid"}); code != http.StatusInternalServerError {
+		t.Fatalf("missing user record: expected 500, got %d", code)
+	}
+}
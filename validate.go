@@ -0,0 +1,97 @@
+package fauth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Validate checks the Engine configuration, returning an error listing all the problems found.
+// It's called by `Auth` and `NewAuthenticator`, surfacing configuration mistakes at startup
+// rather than at request time.
+func (e *Engine) Validate() error {
+	var errs []error
+	if e.AuthScheme != "" && !isToken(e.AuthScheme) {
+		errs = append(errs, fmt.Errorf("AuthScheme %q isn't a valid authentication scheme", e.AuthScheme))
+	}
+	if strings.ContainsFunc(e.Realm, isControl) {
+		errs = append(errs, fmt.Errorf("Realm %q contains control characters", e.Realm))
+	}
+	if e.HealthPath != "" && !strings.HasPrefix(e.HealthPath, "/") {
+		errs = append(errs, fmt.Errorf("HealthPath %q must start with a slash", e.HealthPath))
+	}
+	if e.RequestIDHeader != "" && !isToken(e.RequestIDHeader) {
+		errs = append(errs, fmt.Errorf("RequestIDHeader %q isn't a valid header name", e.RequestIDHeader))
+	}
+	if e.UnauthorizedStatus != 0 && (e.UnauthorizedStatus < 400 || e.UnauthorizedStatus > 599) {
+		errs = append(errs, fmt.Errorf("UnauthorizedStatus %d isn't an error status", e.UnauthorizedStatus))
+	}
+	if e.MaxTokenLifetime < 0 {
+		errs = append(errs, fmt.Errorf("MaxTokenLifetime %s can't be negative", e.MaxTokenLifetime))
+	}
+	if e.DefaultTimeout < 0 {
+		errs = append(errs, fmt.Errorf("DefaultTimeout %s can't be negative", e.DefaultTimeout))
+	}
+	if e.VerifyTimeout < 0 {
+		errs = append(errs, fmt.Errorf("VerifyTimeout %s can't be negative", e.VerifyTimeout))
+	}
+	if e.MaxBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("MaxBodyBytes %d can't be negative", e.MaxBodyBytes))
+	}
+	if e.SessionLifetime != 0 && (e.SessionLifetime < 5*time.Minute || e.SessionLifetime > 14*24*time.Hour) {
+		errs = append(errs, fmt.Errorf("SessionLifetime %s must be between 5 minutes and 2 weeks", e.SessionLifetime))
+	}
+	if e.UserCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("UserCacheTTL %s can't be negative", e.UserCacheTTL))
+	}
+	if e.UserCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("UserCacheSize %d can't be negative", e.UserCacheSize))
+	}
+	if e.TokenCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("TokenCacheTTL %s can't be negative", e.TokenCacheTTL))
+	}
+	if e.TokenCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("TokenCacheSize %d can't be negative", e.TokenCacheSize))
+	}
+	if e.KeyRefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("KeyRefreshInterval %s can't be negative", e.KeyRefreshInterval))
+	}
+	if e.KeyRefreshInterval > 0 && e.ProjectID == "" {
+		errs = append(errs, errors.New("KeyRefreshInterval is set, but there's no ProjectID"))
+	}
+	if e.withoutClose != "" {
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"KeyRefreshInterval", e.KeyRefreshInterval > 0},
+			{"OnActive", e.OnActive != nil},
+			{"InjectFirestore", e.InjectFirestore},
+		} {
+			if f.set {
+				errs = append(errs, fmt.Errorf("%s needs Authenticator.Close, but %s discards the Authenticator, use NewAuthenticator", f.name, e.withoutClose))
+			}
+		}
+	}
+	if e.OnDataFatal && e.LoadProfile == nil {
+		errs = append(errs, errors.New("OnDataFatal is set, but there's no data loading hook, e.g. LoadProfile"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("fauth: invalid engine configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isControl(r rune) bool {
+	return r < ' ' || r == 0x7f
+}
@@ -0,0 +1,66 @@
+package fauth_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+	"github.com/enfunc/fauth/fauthtest"
+)
+
+func TestEngineValidate(t *testing.T) {
+	if err := (&fauth.Engine{}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &fauth.Engine{
+		AuthScheme:         "Bearer JWT",
+		Realm:              "api\r\nX-Injected: true",
+		HealthPath:         "healthz",
+		OnDataFatal:        true,
+		MaxBodyBytes:       -1,
+		RequestIDHeader:    "X Request ID",
+		KeyRefreshInterval: time.Minute,
+		SessionLifetime:    time.Minute,
+		UnauthorizedStatus: http.StatusOK,
+	}
+	err := e.Validate()
+	if err == nil {
+		t.Fatal("the engine should be invalid")
+	}
+	for _, field := range []string{"AuthScheme", "Realm", "HealthPath", "OnDataFatal", "MaxBodyBytes", "RequestIDHeader", "KeyRefreshInterval", "SessionLifetime", "UnauthorizedStatus"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Fatalf("%s should be reported: %v", field, err)
+		}
+	}
+
+	_, err = fauth.Auth(context.Background(), func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnDataFatal = true
+	})
+	if err == nil {
+		t.Fatal("Auth should validate the engine")
+	}
+}
+
+func TestWithoutClose(t *testing.T) {
+	opt := func(e *fauth.Engine) {
+		e.NewApp = fauthtest.NewApp
+		e.OnActive = func(ctx context.Context, uid string) {}
+	}
+	if _, err := fauth.Auth(context.Background(), opt); err == nil || !strings.Contains(err.Error(), "OnActive") ||
+		!strings.Contains(err.Error(), "Auth discards") {
+		t.Fatalf("Auth should reject OnActive, got: %v", err)
+	}
+	if _, err := fauth.SSEHandler(context.Background(), "token", nil, opt); err == nil || !strings.Contains(err.Error(), "SSEHandler") {
+		t.Fatalf("SSEHandler should reject OnActive, got: %v", err)
+	}
+	a, err := fauth.NewAuthenticator(context.Background(), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+}
@@ -0,0 +1,74 @@
+package fauth
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+var errNoClient = errors.New("fauth: no Firebase Auth client in the request context")
+
+// WatchRevocation periodically re-verifies the token of a long-lived request, e.g. a WebSocket or
+// a server-sent events connection, making sure it hasn't been revoked since the connection was opened.
+// Each check is an RPC call, so choose the interval accordingly.
+//
+// The onInvalid func is called once, from the watcher goroutine, as soon as the token is revoked,
+// disabled, expired or otherwise invalid. Use it to close the connection. Transient failures, e.g. network
+// errors, are ignored until the next check.
+//
+// The watcher stops when the request context is done, or when the returned stop func is called.
+// The stop func waits for the watcher goroutine to exit and is safe to call multiple times.
+// WatchRevocation must be called from a handler wrapped by `Auth`, for example:
+//
+//	stop, err := fauth.WatchRevocation(r, time.Minute, func(err error) {
+//		conn.Close()
+//	})
+//	if err != nil {
+//		return
+//	}
+//	defer stop()
+func WatchRevocation(r *http.Request, interval time.Duration, onInvalid func(err error)) (func(), error) {
+	client := scopeFrom(r.Context()).client
+	if client == nil {
+		return nil, errNoClient
+	}
+	jwt, err := ExtractToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				_, err := client.VerifyIDTokenAndCheckRevoked(ctx, jwt)
+				if err == nil || ctx.Err() != nil {
+					continue
+				}
+				if auth.IsIDTokenInvalid(err) || auth.IsUserNotFound(err) {
+					onInvalid(err)
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}, nil
+}
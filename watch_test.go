@@ -0,0 +1,42 @@
+package fauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/enfunc/fauth"
+)
+
+func TestWatchRevocationOutsideAuth(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	if _, err := fauth.WatchRevocation(r, time.Second, func(error) {}); err == nil {
+		t.Fatal("watching outside of Auth should fail")
+	}
+}
+
+func TestWatchRevocation(t *testing.T) {
+	liveTestSetup(t, func(w *httptest.ResponseRecorder, r *http.Request, jwt string) {
+		withFirebaseAuth, err := fauth.Auth(r.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h := withFirebaseAuth(func(w http.ResponseWriter, r *http.Request) {
+			stop, err := fauth.WatchRevocation(r, 10*time.Millisecond, func(err error) {
+				t.Errorf("valid token reported as invalid: %v", err)
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+			stop()
+			stop()
+		})
+
+		h.ServeHTTP(w, r)
+	})
+}